@@ -0,0 +1,186 @@
+// Package main
+// Copyright © 2021 zc2638 <zc2638@qq.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/99nil/ditto"
+)
+
+// errParse marks errors raised while reading or writing the data itself.
+var errParse = errors.New("parse error")
+
+type convertOptions struct {
+	from    string
+	to      string
+	output  string
+	dir     string
+	ordered bool
+}
+
+func runConvert(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	var opts convertOptions
+	fs := flag.NewFlagSet("convert", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.StringVar(&opts.from, "f", "", "input format, inferred from the input file when empty")
+	fs.StringVar(&opts.to, "t", "", "output format, inferred from the -o file when empty")
+	fs.StringVar(&opts.output, "o", "", "output file, defaults to stdout")
+	fs.StringVar(&opts.dir, "d", "", "output directory, one converted file per input file")
+	fs.BoolVar(&opts.ordered, "ordered", false, "keep the source order of keys instead of sorting them")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: ditto convert [-f format] [-t format] [-ordered] [-o file | -d dir] [files...]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return exitOK
+		}
+		return exitUsage
+	}
+	files := fs.Args()
+	if opts.output != "" && opts.dir != "" {
+		fmt.Fprintln(stderr, "ditto: -o and -d are mutually exclusive")
+		return exitUsage
+	}
+	if len(files) > 1 && opts.dir == "" {
+		fmt.Fprintln(stderr, "ditto: converting several files requires -d")
+		return exitUsage
+	}
+
+	if opts.to == "" && opts.output != "" {
+		to, err := ditto.DetectFormat(opts.output, nil)
+		if err != nil {
+			return exitCode(stderr, fmt.Errorf("output %s: %w", opts.output, err))
+		}
+		opts.to = to
+	}
+	if opts.to == "" {
+		fmt.Fprintln(stderr, "ditto: missing output format, use -t")
+		return exitUsage
+	}
+
+	if len(files) == 0 {
+		data, err := ioutil.ReadAll(stdin)
+		if err != nil {
+			return exitCode(stderr, err)
+		}
+		out, err := convert(opts, "", data)
+		if err != nil {
+			return exitCode(stderr, fmt.Errorf("stdin: %w", err))
+		}
+		return exitCode(stderr, writeOutput(opts.output, stdout, out))
+	}
+
+	if opts.dir != "" {
+		// refuse to let inputs sharing a base name overwrite each other
+		sources := make(map[string]string, len(files))
+		for _, file := range files {
+			name := outputName(file, opts.to)
+			if prev, ok := sources[name]; ok {
+				fmt.Fprintf(stderr, "ditto: %s and %s both convert to %s\n", prev, file, filepath.Join(opts.dir, name))
+				return exitUsage
+			}
+			sources[name] = file
+		}
+	}
+
+	code := exitOK
+	for _, file := range files {
+		if err := convertFile(opts, file, stdout); err != nil {
+			// keep converting the remaining files, report the first failure
+			if c := exitCode(stderr, err); code == exitOK {
+				code = c
+			}
+		}
+	}
+	return code
+}
+
+func convertFile(opts convertOptions, file string, stdout io.Writer) error {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	out, err := convert(opts, file, data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", file, err)
+	}
+	if opts.dir == "" {
+		return writeOutput(opts.output, stdout, out)
+	}
+	name := outputName(file, opts.to)
+	if err := os.MkdirAll(opts.dir, 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(opts.dir, name), out, 0644)
+}
+
+func convert(opts convertOptions, filename string, data []byte) ([]byte, error) {
+	from := opts.from
+	if from == "" {
+		detected, err := ditto.DetectFormat(filename, data)
+		if err != nil {
+			return nil, err
+		}
+		from = detected
+	}
+	var transferOpts []ditto.TransferOption
+	if opts.ordered {
+		transferOpts = append(transferOpts, ditto.WithOrderedKeys())
+	}
+	out, err := ditto.NewTransfer(from, opts.to, transferOpts...).Exchange(data)
+	if err != nil {
+		var engineErr *ditto.EngineError
+		if errors.As(err, &engineErr) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: %v", errParse, err)
+	}
+	return out, nil
+}
+
+func writeOutput(output string, stdout io.Writer, data []byte) error {
+	if len(data) > 0 && data[len(data)-1] != '\n' {
+		data = append(data, '\n')
+	}
+	if output == "" {
+		_, err := stdout.Write(data)
+		return err
+	}
+	return ioutil.WriteFile(output, data, 0644)
+}
+
+// outputName returns the name of the file converted from file in the -d directory.
+func outputName(file, format string) string {
+	return strings.TrimSuffix(filepath.Base(file), filepath.Ext(file)) + extension(format)
+}
+
+// extension returns the preferred file extension of the format.
+func extension(format string) string {
+	if e, ok := ditto.DefaultRegistry().Get(format); ok {
+		if exts := e.Extensions(); len(exts) > 0 {
+			return exts[0]
+		}
+	}
+	return "." + format
+}
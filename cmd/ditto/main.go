@@ -0,0 +1,81 @@
+// Package main
+// Copyright © 2021 zc2638 <zc2638@qq.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/99nil/ditto"
+)
+
+// Exit codes returned by the ditto command.
+const (
+	exitOK            = 0
+	exitError         = 1
+	exitUsage         = 2
+	exitUnknownFormat = 3
+	exitParse         = 4
+)
+
+const usage = `Usage: ditto <command> [flags] [files...]
+
+Commands:
+  convert   convert data from one format to another
+  validate  check data for syntax errors
+`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return exitUsage
+	}
+	switch args[0] {
+	case "convert":
+		return runConvert(args[1:], stdin, stdout, stderr)
+	case "validate":
+		return runValidate(args[1:], stdin, stdout, stderr)
+	case "-h", "-help", "--help", "help":
+		fmt.Fprint(stdout, usage)
+		return exitOK
+	default:
+		fmt.Fprintf(stderr, "ditto: unknown command %q\n%s", args[0], usage)
+		return exitUsage
+	}
+}
+
+// exitCode maps err to the exit code of the command.
+func exitCode(stderr io.Writer, err error) int {
+	if err == nil {
+		return exitOK
+	}
+	fmt.Fprintf(stderr, "ditto: %v\n", err)
+	var pathErr *os.PathError
+	switch {
+	case errors.Is(err, ditto.ErrUnknownFormat), errors.Is(err, ditto.ErrEngineNotFound):
+		return exitUnknownFormat
+	case errors.As(err, &pathErr):
+		return exitError
+	case errors.Is(err, errParse):
+		return exitParse
+	}
+	return exitError
+}
@@ -0,0 +1,126 @@
+// Package main
+// Copyright © 2021 zc2638 <zc2638@qq.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(dir, "a.yaml"), []byte("name: a\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "b.yml"), []byte("name: b\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "b.json"), []byte(`{"name":"b2"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	outDir := filepath.Join(dir, "out")
+
+	tests := []struct {
+		name       string
+		args       []string
+		stdin      string
+		wantCode   int
+		wantStdout string
+	}{
+		{
+			name:       "stdin-to-stdout",
+			args:       []string{"convert", "-f", "yaml", "-t", "json"},
+			stdin:      "name: zc\n",
+			wantCode:   exitOK,
+			wantStdout: "{\"name\":\"zc\"}\n",
+		},
+		{
+			name:       "multi-document-stdin",
+			args:       []string{"convert", "-f", "yaml", "-t", "json"},
+			stdin:      "kind: A\n---\nkind: B\n",
+			wantCode:   exitOK,
+			wantStdout: "{\"kind\":\"A\"}\n{\"kind\":\"B\"}\n",
+		},
+		{
+			name:       "infer-input-format",
+			args:       []string{"convert", "-t", "json", filepath.Join(dir, "a.yaml")},
+			wantCode:   exitOK,
+			wantStdout: "{\"name\":\"a\"}\n",
+		},
+		{
+			name:     "output-directory",
+			args:     []string{"convert", "-t", "toml", "-d", outDir, filepath.Join(dir, "a.yaml"), filepath.Join(dir, "b.yml")},
+			wantCode: exitOK,
+		},
+		{
+			name:     "output-name-collision",
+			args:     []string{"convert", "-t", "toml", "-d", filepath.Join(dir, "collide"), filepath.Join(dir, "b.yml"), filepath.Join(dir, "b.json")},
+			wantCode: exitUsage,
+		},
+		{
+			name:     "parse-error",
+			args:     []string{"convert", "-f", "json", "-t", "yaml"},
+			stdin:    "{bad",
+			wantCode: exitParse,
+		},
+		{
+			name:     "unknown-format",
+			args:     []string{"convert", "-f", "nope", "-t", "yaml"},
+			stdin:    "x",
+			wantCode: exitUnknownFormat,
+		},
+		{
+			name:     "validate-ok",
+			args:     []string{"validate", filepath.Join(dir, "a.yaml")},
+			wantCode: exitOK,
+		},
+		{
+			name:       "validate-error",
+			args:       []string{"validate", "-f", "json"},
+			stdin:      `{"a" 1}`,
+			wantCode:   exitParse,
+			wantStdout: "<stdin>:1:6: expected ':' after object key at $.a\n{\"a\" 1}\n     ^\n",
+		},
+		{
+			name:     "missing-output-format",
+			args:     []string{"convert", "-f", "json"},
+			wantCode: exitUsage,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+			code := run(tt.args, strings.NewReader(tt.stdin), stdout, stderr)
+			if code != tt.wantCode {
+				t.Fatalf("run() code = %v, want %v, stderr = %s", code, tt.wantCode, stderr)
+			}
+			if tt.wantStdout != "" && stdout.String() != tt.wantStdout {
+				t.Errorf("run() stdout = %q, want %q", stdout, tt.wantStdout)
+			}
+		})
+	}
+
+	for _, name := range []string{"a.toml", "b.toml"} {
+		if _, err := ioutil.ReadFile(filepath.Join(outDir, name)); err != nil {
+			t.Errorf("missing converted file: %v", err)
+		}
+	}
+	if _, err := ioutil.ReadDir(filepath.Join(dir, "collide")); err == nil {
+		t.Errorf("colliding inputs were converted")
+	}
+}
@@ -0,0 +1,93 @@
+// Package main
+// Copyright © 2021 zc2638 <zc2638@qq.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/99nil/ditto"
+)
+
+func runValidate(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	var format string
+	fs := flag.NewFlagSet("validate", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.StringVar(&format, "f", "", "input format, inferred from the input file when empty")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: ditto validate [-f format] [files...]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return exitOK
+		}
+		return exitUsage
+	}
+
+	files := fs.Args()
+	if len(files) == 0 {
+		data, err := ioutil.ReadAll(stdin)
+		if err != nil {
+			return exitCode(stderr, err)
+		}
+		return validate(format, "<stdin>", "", data, stdout, stderr)
+	}
+	code := exitOK
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			if c := exitCode(stderr, err); code == exitOK {
+				code = c
+			}
+			continue
+		}
+		if c := validate(format, file, file, data, stdout, stderr); code == exitOK {
+			code = c
+		}
+	}
+	return code
+}
+
+// validate prints the diagnostics of data, labelled by name.
+func validate(format, name, filename string, data []byte, stdout, stderr io.Writer) int {
+	if format == "" {
+		detected, err := ditto.DetectFormat(filename, data)
+		if err != nil {
+			return exitCode(stderr, fmt.Errorf("%s: %w", name, err))
+		}
+		format = detected
+	}
+	if _, ok := ditto.DefaultRegistry().Get(format); !ok {
+		return exitCode(stderr, &ditto.EngineError{
+			Name:      format,
+			Direction: ditto.DirectionInput,
+			Err:       ditto.ErrEngineNotFound,
+		})
+	}
+	diagnostics := ditto.Validate(format, data)
+	for _, d := range diagnostics {
+		fmt.Fprintf(stdout, "%s:%v\n", name, d)
+		if d.Snippet != "" {
+			fmt.Fprintln(stdout, d.Snippet)
+		}
+	}
+	if len(diagnostics) > 0 {
+		return exitParse
+	}
+	return exitOK
+}
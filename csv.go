@@ -0,0 +1,94 @@
+// Package ditto
+// Copyright © 2021 zc2638 <zc2638@qq.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package ditto
+
+import (
+	"io"
+
+	csve "github.com/99nil/ditto/csv"
+)
+
+// The functions of the csv package separate fields with commas by default,
+// the TSV ones below separate them with tabs unless the options of a Document say otherwise.
+
+// tsv returns the document of v.
+func tsv(v interface{}) *csve.Document {
+	var doc csve.Document
+	switch val := v.(type) {
+	case csve.Document:
+		doc = val
+	case *csve.Document:
+		doc = *val
+	default:
+		doc.Value = v
+	}
+	if doc.Comma == 0 {
+		doc.Comma = '\t'
+	}
+	return &doc
+}
+
+// tsvDecode decodes a table into v with decode.
+func tsvDecode(v interface{}, decode func(v interface{}) error) error {
+	switch val := v.(type) {
+	case *csve.Document:
+		if val.Comma == 0 {
+			val.Comma = '\t'
+			defer func() { val.Comma = 0 }()
+		}
+	case *interface{}:
+		doc := &csve.Document{Options: csve.Options{Comma: '\t'}}
+		if err := decode(doc); err != nil {
+			return err
+		}
+		*val = doc.Value
+		return nil
+	}
+	return decode(v)
+}
+
+func tsvMarshal(v interface{}) ([]byte, error) {
+	return csve.Marshal(tsv(v))
+}
+
+func tsvUnmarshal(data []byte, v interface{}) error {
+	return tsvDecode(v, func(v interface{}) error {
+		return csve.Unmarshal(data, v)
+	})
+}
+
+type tsvEncoder struct {
+	enc *csve.Encoder
+}
+
+func newTSVEncoder(w io.Writer) Encoder {
+	return tsvEncoder{enc: csve.NewEncoder(w)}
+}
+
+func (e tsvEncoder) Encode(v interface{}) error {
+	return e.enc.Encode(tsv(v))
+}
+
+type tsvDecoder struct {
+	dec *csve.Decoder
+}
+
+func newTSVDecoder(r io.Reader) Decoder {
+	return tsvDecoder{dec: csve.NewDecoder(r)}
+}
+
+func (d tsvDecoder) Decode(v interface{}) error {
+	return tsvDecode(v, d.dec.Decode)
+}
@@ -0,0 +1,343 @@
+// Package csv
+// Copyright © 2021 zc2638 <zc2638@qq.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package csv
+
+import (
+	csve "encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/99nil/ditto/flat"
+	"github.com/99nil/ditto/ordered"
+)
+
+// NestedMode selects how the nested values of records are written in columns.
+type NestedMode int
+
+const (
+	// NestedDotted writes the leaves of nested values in columns named by their path,
+	// such as "owner.name" or "tags.0", and nests such columns back when decoding.
+	// The cells of a nested value being all empty, as in the records lacking it, make no value.
+	NestedDotted NestedMode = iota
+	// NestedJSON writes nested values as JSON in a single column,
+	// and decodes the cells holding a JSON object or array.
+	NestedJSON
+)
+
+// Options configures how tables are decoded and encoded.
+type Options struct {
+	// Comma is the field delimiter, ',' when zero.
+	Comma rune
+	// NoHeader reads and writes tables without a header row,
+	// the records being arrays of cells instead of maps keyed by the header.
+	NoHeader bool
+	// Nested selects how nested values are written in columns, NestedDotted by default.
+	Nested NestedMode
+	// Separator joins the keys of the columns of nested values, "." when empty.
+	Separator string
+	// InferTypes decodes cells looking like integers, floats, booleans or null
+	// as int64, float64, bool or nil instead of strings.
+	InferTypes bool
+}
+
+// ErrFieldCount reports a record having more cells than the header.
+var ErrFieldCount = errors.New("wrong number of fields")
+
+// bom starts the files written by some spreadsheet applications.
+const bom = "\ufeff"
+
+func (o *Options) comma() rune {
+	if o.Comma == 0 {
+		return ','
+	}
+	return o.Comma
+}
+
+func (o *Options) flat(ordered bool) flat.Options {
+	return flat.Options{Separator: o.Separator, Ordered: ordered}
+}
+
+// Document is a table with the options to decode and encode it.
+// Its value is a []interface{} of records, maps keyed by the header row,
+// or arrays of cells without a header. A single map is encoded as a table of one record.
+type Document struct {
+	Options
+	// Ordered decodes records as ordered.Maps following the columns.
+	Ordered bool
+	Value   interface{}
+}
+
+// read decodes the table of r.
+func (doc *Document) read(r io.Reader) error {
+	cr := csve.NewReader(r)
+	cr.Comma = doc.comma()
+	cr.FieldsPerRecord = -1
+
+	var header []string
+	records := make([]interface{}, 0)
+	for n := 1; ; n++ {
+		row, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if n == 1 && len(row) > 0 {
+			row[0] = strings.TrimPrefix(row[0], bom)
+		}
+		if doc.NoHeader {
+			cells := make([]interface{}, 0, len(row))
+			for _, cell := range row {
+				cells = append(cells, doc.value(cell))
+			}
+			records = append(records, cells)
+			continue
+		}
+		if header == nil {
+			header = row
+			continue
+		}
+		record, err := doc.record(header, row)
+		if err != nil {
+			return fmt.Errorf("record %d: %w", n-1, err)
+		}
+		records = append(records, record)
+	}
+	doc.Value = records
+	return nil
+}
+
+// record returns the record of a row keyed by the header.
+func (doc *Document) record(header, row []string) (interface{}, error) {
+	if len(row) > len(header) {
+		return nil, fmt.Errorf("%w: %d, the header has %d", ErrFieldCount, len(row), len(header))
+	}
+	filled := doc.filled(header, row)
+	items := make(ordered.Map, 0, len(row))
+	for i, cell := range row {
+		// the empty cells of a nested value the record does not have make no value
+		if parent, ok := doc.parent(header[i]); ok && cell == "" && !filled[parent] {
+			continue
+		}
+		items = append(items, ordered.Item{Key: header[i], Value: doc.value(cell)})
+	}
+	if doc.Nested == NestedDotted && len(items) > 0 {
+		return flat.Unflatten(items, doc.flat(doc.Ordered))
+	}
+	if doc.Ordered {
+		return items, nil
+	}
+	m := make(map[string]interface{}, len(items))
+	for _, item := range items {
+		m[item.Key] = item.Value
+	}
+	return m, nil
+}
+
+// parent returns the path of the nested value holding the column key in NestedDotted mode.
+func (o *Options) parent(key string) (string, bool) {
+	if o.Nested != NestedDotted {
+		return "", false
+	}
+	sep := o.Separator
+	if sep == "" {
+		sep = flat.DefaultSeparator
+	}
+	i := strings.LastIndex(key, sep)
+	if i < 0 {
+		return "", false
+	}
+	return key[:i], true
+}
+
+// filled returns the paths of the nested values having a cell that is not empty in row.
+func (o *Options) filled(header, row []string) map[string]bool {
+	paths := make(map[string]bool)
+	for i, cell := range row {
+		if cell == "" {
+			continue
+		}
+		for key, ok := o.parent(header[i]); ok && !paths[key]; key, ok = o.parent(key) {
+			paths[key] = true
+		}
+	}
+	return paths
+}
+
+// value decodes a cell.
+func (doc *Document) value(cell string) interface{} {
+	text := strings.TrimSpace(cell)
+	if doc.Nested == NestedJSON && (strings.HasPrefix(text, "{") || strings.HasPrefix(text, "[")) {
+		if doc.Ordered {
+			var v ordered.Value
+			if err := json.Unmarshal([]byte(text), &v); err == nil {
+				return v.V
+			}
+		} else {
+			var v interface{}
+			if err := json.Unmarshal([]byte(text), &v); err == nil {
+				return v
+			}
+		}
+	}
+	if !doc.InferTypes {
+		return cell
+	}
+	switch text {
+	case "true":
+		return true
+	case "false":
+		return false
+	case "null":
+		return nil
+	}
+	if isNumber(text) {
+		if i, err := strconv.ParseInt(text, 10, 64); err == nil {
+			return i
+		}
+		if f, err := strconv.ParseFloat(text, 64); err == nil {
+			return f
+		}
+	}
+	return cell
+}
+
+// isNumber reports whether s is a number without leading zeros, which codes such as 007 have.
+func isNumber(s string) bool {
+	digits := strings.TrimLeft(s, "+-")
+	if digits == "" || (len(digits) > 1 && digits[0] == '0' && digits[1] != '.') {
+		return false
+	}
+	for i := 0; i < len(digits); i++ {
+		switch c := digits[i]; {
+		case c >= '0' && c <= '9':
+		case c == '.' || c == 'e' || c == 'E' || c == '-' || c == '+':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// rows returns the rows of the table, the header first.
+func (doc *Document) rows() ([][]string, error) {
+	var records []interface{}
+	switch val := doc.Value.(type) {
+	case nil:
+	case []interface{}:
+		records = val
+	case map[string]interface{}, ordered.Map:
+		records = []interface{}{val}
+	default:
+		return nil, fmt.Errorf("cannot encode %T as a table", doc.Value)
+	}
+
+	var (
+		rows    [][]string
+		columns []string
+		index   = make(map[string]int)
+		fields  = make([]ordered.Map, 0, len(records))
+	)
+	for i, record := range records {
+		switch val := record.(type) {
+		case []interface{}:
+			row := make([]string, 0, len(val))
+			for _, v := range val {
+				cell, err := doc.cell(v)
+				if err != nil {
+					return nil, fmt.Errorf("record %d: %w", i+1, err)
+				}
+				row = append(row, cell)
+			}
+			rows = append(rows, row)
+			continue
+		case map[string]interface{}, ordered.Map:
+		default:
+			return nil, fmt.Errorf("record %d: cannot encode %T as a record", i+1, record)
+		}
+		var items ordered.Map
+		if doc.Nested == NestedDotted {
+			items = flat.Flatten(record, doc.flat(false))
+		} else {
+			items = topLevel(record)
+		}
+		for _, item := range items {
+			if _, ok := index[item.Key]; !ok {
+				index[item.Key] = len(columns)
+				columns = append(columns, item.Key)
+			}
+		}
+		fields = append(fields, items)
+	}
+	if len(rows) > 0 && len(fields) > 0 {
+		return nil, errors.New("cannot encode both records and arrays of cells as a table")
+	}
+	if len(fields) == 0 {
+		return rows, nil
+	}
+
+	if !doc.NoHeader {
+		rows = append(rows, columns)
+	}
+	for i, items := range fields {
+		row := make([]string, len(columns))
+		for _, item := range items {
+			cell, err := doc.cell(item.Value)
+			if err != nil {
+				return nil, fmt.Errorf("record %d: %w", i+1, err)
+			}
+			row[index[item.Key]] = cell
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// topLevel returns the items of a record map in order, its keys sorted when it is a map.
+func topLevel(record interface{}) ordered.Map {
+	if m, ok := record.(ordered.Map); ok {
+		return m
+	}
+	m := record.(map[string]interface{})
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	items := make(ordered.Map, 0, len(m))
+	for _, key := range keys {
+		items = append(items, ordered.Item{Key: key, Value: m[key]})
+	}
+	return items
+}
+
+// cell encodes a value in a cell, nested values as JSON.
+func (doc *Document) cell(v interface{}) (string, error) {
+	switch val := v.(type) {
+	case map[string]interface{}, ordered.Map, []interface{}:
+		data, err := json.Marshal(val)
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+	}
+	return flat.Text(v), nil
+}
@@ -0,0 +1,207 @@
+// Package csv
+// Copyright © 2021 zc2638 <zc2638@qq.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package csv
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/99nil/ditto/ordered"
+)
+
+func TestUnmarshal(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    Options
+		ordered bool
+		data    string
+		want    interface{}
+		wantErr error
+	}{
+		{
+			name: "header",
+			data: "\ufeffname,owner.name,tags.0,tags.1\nditto,zc,a,b\nyaml,,c,\n",
+			want: []interface{}{
+				map[string]interface{}{
+					"name":  "ditto",
+					"owner": map[string]interface{}{"name": "zc"},
+					"tags":  []interface{}{"a", "b"},
+				},
+				map[string]interface{}{
+					"name": "yaml",
+					"tags": []interface{}{"c", ""},
+				},
+			},
+		},
+		{
+			name:    "ordered",
+			ordered: true,
+			data:    "b,a\n1,2\n",
+			want:    []interface{}{ordered.Map{{Key: "b", Value: "1"}, {Key: "a", Value: "2"}}},
+		},
+		{
+			name: "infer types",
+			opts: Options{InferTypes: true},
+			data: "id,price,ok,zip,note\n1,9.5,true,007,null\n",
+			want: []interface{}{
+				map[string]interface{}{"id": int64(1), "price": 9.5, "ok": true, "zip": "007", "note": nil},
+			},
+		},
+		{
+			name: "embedded json",
+			opts: Options{Nested: NestedJSON, Comma: ';'},
+			data: "name;owner;tags\nditto;\"{\"\"name\"\":\"\"zc\"\"}\";[1]\n",
+			want: []interface{}{
+				map[string]interface{}{
+					"name":  "ditto",
+					"owner": map[string]interface{}{"name": "zc"},
+					"tags":  []interface{}{float64(1)},
+				},
+			},
+		},
+		{
+			name: "no header",
+			opts: Options{NoHeader: true, Comma: '\t'},
+			data: "a\tb\nc\n",
+			want: []interface{}{[]interface{}{"a", "b"}, []interface{}{"c"}},
+		},
+		{
+			name:    "too many fields",
+			data:    "a\n1,2\n",
+			wantErr: ErrFieldCount,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := &Document{Options: tt.opts, Ordered: tt.ordered}
+			err := Unmarshal([]byte(tt.data), doc)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Unmarshal() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && !reflect.DeepEqual(doc.Value, tt.want) {
+				t.Errorf("Unmarshal() = %#v, want %#v", doc.Value, tt.want)
+			}
+		})
+	}
+}
+
+func TestMarshal_RoundTrip(t *testing.T) {
+	// the records have different columns, the missing ones being empty cells,
+	// so a nested value holding only empty strings is missing too
+	const src = `[{"a":1,"b":{"c":"x,y"}},{"a":2,"d":[1,2]},{"a":3,"d":["",3],"e":{"f":{"g":""},"h":"i"}}]`
+	const want = `[{"a":"1","b":{"c":"x,y"}},{"a":"2","d":["1","2"]},{"a":"3","d":["","3"],"e":{"h":"i"}}]`
+	var v interface{}
+	if err := json.Unmarshal([]byte(src), &v); err != nil {
+		t.Fatal(err)
+	}
+	data, err := Marshal(Document{Value: v})
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	doc := &Document{}
+	if err := Unmarshal(data, doc); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	got, err := json.Marshal(doc.Value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Errorf("round trip = %s, want %s\ncsv:\n%s", got, want, data)
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	records := []interface{}{
+		map[string]interface{}{
+			"name":  "ditto",
+			"owner": map[string]interface{}{"name": "zc"},
+			"tags":  []interface{}{"a", "b"},
+			"stars": float64(1200),
+		},
+		ordered.Map{
+			{Key: "name", Value: "yaml, v2"},
+			{Key: "fork", Value: true},
+		},
+	}
+	tests := []struct {
+		name    string
+		opts    Options
+		value   interface{}
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "dotted",
+			value: records,
+			want: "name,owner.name,stars,tags.0,tags.1,fork\n" +
+				"ditto,zc,1200,a,b,\n" +
+				"\"yaml, v2\",,,,,true\n",
+		},
+		{
+			name:  "embedded json",
+			opts:  Options{Nested: NestedJSON, Comma: '\t'},
+			value: records,
+			want: "name\towner\tstars\ttags\tfork\n" +
+				"ditto\t\"{\"\"name\"\":\"\"zc\"\"}\"\t1200\t\"[\"\"a\"\",\"\"b\"\"]\"\t\n" +
+				"yaml, v2\t\t\t\ttrue\n",
+		},
+		{
+			name:  "single record without header",
+			opts:  Options{NoHeader: true},
+			value: map[string]interface{}{"a": 1, "b": nil},
+			want:  "1,\n",
+		},
+		{
+			name:  "arrays of cells",
+			value: []interface{}{[]interface{}{"a", float64(1)}, []interface{}{}},
+			want:  "a,1\n\n",
+		},
+		{
+			name:    "scalar records",
+			value:   []interface{}{"a"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Marshal(Document{Options: tt.opts, Value: tt.value})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Marshal() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Marshal() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecoder_Decode(t *testing.T) {
+	dec := NewDecoder(strings.NewReader("a\n1\n"))
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if want := []interface{}{map[string]interface{}{"a": "1"}}; !reflect.DeepEqual(v, want) {
+		t.Errorf("Decode() = %#v, want %#v", v, want)
+	}
+	if err := dec.Decode(&v); err != io.EOF {
+		t.Errorf("Decode() error = %v, want EOF", err)
+	}
+}
@@ -0,0 +1,63 @@
+// Package csv
+// Copyright © 2021 zc2638 <zc2638@qq.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package csv
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// Unmarshal decodes the table of data into v,
+// a *Document or an *interface{} decoded with the default options.
+func Unmarshal(data []byte, v interface{}) error {
+	return NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+// Decoder reads a table from an input stream, see Unmarshal.
+type Decoder struct {
+	r    io.Reader
+	done bool
+}
+
+// NewDecoder returns a new decoder that reads from r.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: r}
+}
+
+// Decode reads the table of the input and stores it in v.
+// The input holds a single table, Decode returns io.EOF once it is read.
+func (dec *Decoder) Decode(v interface{}) error {
+	if dec.done {
+		return io.EOF
+	}
+	var doc *Document
+	switch val := v.(type) {
+	case *Document:
+		doc = val
+	case *interface{}:
+		doc = &Document{}
+	default:
+		return fmt.Errorf("cannot decode a table into %T", v)
+	}
+	if err := doc.read(dec.r); err != nil {
+		return err
+	}
+	dec.done = true
+	if p, ok := v.(*interface{}); ok {
+		*p = doc.Value
+	}
+	return nil
+}
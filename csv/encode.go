@@ -0,0 +1,68 @@
+// Package csv
+// Copyright © 2021 zc2638 <zc2638@qq.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package csv
+
+import (
+	"bytes"
+	csve "encoding/csv"
+	"io"
+)
+
+// Marshal returns the table of v, a Document or a value encoded with the default options.
+func Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Encoder writes tables to an output stream, see Marshal.
+type Encoder struct {
+	w io.Writer
+}
+
+// NewEncoder returns a new encoder that writes to w.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: w}
+}
+
+// Encode writes the table of v to the stream.
+func (enc *Encoder) Encode(v interface{}) error {
+	doc := document(v)
+	rows, err := doc.rows()
+	if err != nil {
+		return err
+	}
+	cw := csve.NewWriter(enc.w)
+	cw.Comma = doc.comma()
+	if err := cw.WriteAll(rows); err != nil {
+		return err
+	}
+	return cw.Error()
+}
+
+// document returns v as a Document.
+func document(v interface{}) *Document {
+	switch doc := v.(type) {
+	case Document:
+		return &doc
+	case *Document:
+		if doc != nil {
+			return doc
+		}
+	}
+	return &Document{Value: v}
+}
@@ -0,0 +1,165 @@
+// Package ditto
+// Copyright © 2021 zc2638 <zc2638@qq.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package ditto
+
+import (
+	"bytes"
+	"encoding/xml"
+	"mime"
+	"path/filepath"
+	"strings"
+
+	jsone "github.com/99nil/ditto/json"
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v2"
+)
+
+// DetectFormat guesses the format of data with the default registry.
+// See Registry.DetectFormat.
+func DetectFormat(filename string, data []byte) (string, error) {
+	return defaultRegistry.DetectFormat(filename, data)
+}
+
+// DetectFormat guesses the registered format name of data.
+// filename may be a file name or path, whose extension is looked up first,
+// or a MIME type such as "application/json; charset=utf-8".
+// When neither is conclusive the content of data is sniffed.
+func (r *Registry) DetectFormat(filename string, data []byte) (string, error) {
+	if filename != "" {
+		if name, ok := r.formatByExtension(filepath.Ext(filename)); ok {
+			return name, nil
+		}
+		if name, ok := r.formatByMIMEType(filename); ok {
+			return name, nil
+		}
+	}
+	for _, sniff := range sniffers {
+		if _, ok := r.Get(sniff.name); ok && sniff.match(data) {
+			return sniff.name, nil
+		}
+	}
+	return "", ErrUnknownFormat
+}
+
+func (r *Registry) formatByExtension(ext string) (string, bool) {
+	if ext == "" {
+		return "", false
+	}
+	ext = strings.ToLower(ext)
+	return r.find(func(e *Engine) []string { return e.extensions }, ext)
+}
+
+func (r *Registry) formatByMIMEType(typ string) (string, bool) {
+	typ, _, err := mime.ParseMediaType(typ)
+	if err != nil || !strings.Contains(typ, "/") {
+		return "", false
+	}
+	if name, ok := r.find(func(e *Engine) []string { return e.mimeTypes }, typ); ok {
+		return name, true
+	}
+	// structured syntax suffixes, e.g. application/ld+json
+	if i := strings.LastIndexByte(typ, '+'); i >= 0 {
+		suffix := typ[i+1:]
+		return r.find(func(e *Engine) []string {
+			subtypes := make([]string, 0, len(e.mimeTypes))
+			for _, t := range e.mimeTypes {
+				subtypes = append(subtypes, t[strings.IndexByte(t, '/')+1:])
+			}
+			return subtypes
+		}, suffix)
+	}
+	return "", false
+}
+
+func (r *Registry) find(values func(e *Engine) []string, target string) (string, bool) {
+	for _, name := range r.Names() {
+		e, ok := r.Get(name)
+		if !ok {
+			continue
+		}
+		for _, v := range values(e) {
+			if v == target {
+				return name, true
+			}
+		}
+	}
+	return "", false
+}
+
+// sniffers are tried in order, stricter formats first,
+// since YAML accepts almost any input.
+var sniffers = []struct {
+	name  string
+	match func(data []byte) bool
+}{
+	{name: FormatJSON, match: sniffJSON},
+	{name: FormatJSONC, match: sniffLenientJSON(jsone.JSONC)},
+	{name: FormatJSON5, match: sniffLenientJSON(jsone.JSON5)},
+	{name: FormatXML, match: sniffXML},
+	{name: FormatTOML, match: sniffTOML},
+	{name: FormatYaml, match: sniffYaml},
+}
+
+func sniffJSON(data []byte) bool {
+	_, _, ok := jsone.CheckBytes(data)
+	return ok
+}
+
+func sniffLenientJSON(mode jsone.Mode) func(data []byte) bool {
+	return func(data []byte) bool {
+		return jsone.ValidateMode(data, mode) == nil
+	}
+}
+
+// sniffXML reports whether data starts with a root element,
+// optionally preceded by a declaration, comments or a doctype.
+func sniffXML(data []byte) bool {
+	data = bytes.TrimSpace(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")))
+	if len(data) == 0 || data[0] != '<' {
+		return false
+	}
+	d := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return false
+		}
+		switch tv := token.(type) {
+		case xml.StartElement:
+			return true
+		case xml.CharData:
+			if len(bytes.TrimSpace(tv)) > 0 {
+				return false
+			}
+		}
+	}
+}
+
+func sniffTOML(data []byte) bool {
+	var v map[string]interface{}
+	return toml.Unmarshal(data, &v) == nil && len(v) > 0
+}
+
+func sniffYaml(data []byte) bool {
+	var v interface{}
+	if err := yaml.Unmarshal(data, &v); err != nil {
+		return false
+	}
+	switch v.(type) {
+	case map[interface{}]interface{}, []interface{}:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,77 @@
+// Package ditto
+// Copyright © 2021 zc2638 <zc2638@qq.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package ditto
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestDetectFormat(t *testing.T) {
+	type args struct {
+		filename string
+		data     []byte
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    string
+		wantErr error
+	}{
+		{name: "ext-json", args: args{filename: "conf/app.JSON"}, want: FormatJSON},
+		{name: "ext-yml", args: args{filename: "app.yml"}, want: FormatYaml},
+		{name: "ext-toml", args: args{filename: "app.toml"}, want: FormatTOML},
+		{name: "ext-xml", args: args{filename: "app.xml"}, want: FormatXML},
+		{name: "mime-json", args: args{filename: "application/json; charset=utf-8"}, want: FormatJSON},
+		{name: "mime-yaml", args: args{filename: "text/yaml"}, want: FormatYaml},
+		{name: "mime-suffix", args: args{filename: "application/atom+xml"}, want: FormatXML},
+		{name: "sniff-json", args: args{filename: "app.conf", data: []byte(jsonStr)}, want: FormatJSON},
+		{name: "sniff-jsonc", args: args{data: []byte(jsoncStr)}, want: FormatJSONC},
+		{name: "sniff-json5", args: args{data: []byte(json5Str)}, want: FormatJSON5},
+		{name: "sniff-xml", args: args{data: []byte(`<?xml version="1.0"?>` + xmlStr)}, want: FormatXML},
+		{name: "sniff-toml", args: args{data: []byte(tomlStr)}, want: FormatTOML},
+		{name: "sniff-yaml", args: args{data: []byte(yamlStr)}, want: FormatYaml},
+		{name: "unknown", args: args{data: []byte("plain text")}, wantErr: ErrUnknownFormat},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DetectFormat(tt.args.filename, tt.args.data)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("DetectFormat() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("DetectFormat() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegistry_DetectFormat(t *testing.T) {
+	r := NewRegistry()
+	r.Register("custom", json.Marshal, json.Unmarshal,
+		WithExtensions("cst"),
+		WithMIMETypes("Application/X-Custom"),
+	)
+	for _, filename := range []string{"a.cst", "application/x-custom"} {
+		if got, err := r.DetectFormat(filename, nil); err != nil || got != "custom" {
+			t.Errorf("DetectFormat(%q) = %v, %v, want custom", filename, got, err)
+		}
+	}
+	// sniffing only yields formats registered in the registry
+	if _, err := r.DetectFormat("", []byte(jsonStr)); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("DetectFormat() error = %v, want %v", err, ErrUnknownFormat)
+	}
+}
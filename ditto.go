@@ -15,32 +15,104 @@
 package ditto
 
 import (
+	"bytes"
 	"encoding/json"
-	"encoding/xml"
-	"errors"
 	"fmt"
 	"io"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
 
+	csve "github.com/99nil/ditto/csv"
+	dotenve "github.com/99nil/ditto/dotenv"
+	inie "github.com/99nil/ditto/ini"
+	jsone "github.com/99nil/ditto/json"
+	ndjsone "github.com/99nil/ditto/ndjson"
+	"github.com/99nil/ditto/ordered"
+	propertiese "github.com/99nil/ditto/properties"
 	xmle "github.com/99nil/ditto/xml"
 	"github.com/pelletier/go-toml/v2"
 	"gopkg.in/yaml.v2"
 )
 
-var set = make(map[string]*Engine)
-
 const (
 	FormatJSON = "json"
 	FormatYaml = "yaml"
 	FormatXML  = "xml"
 	FormatTOML = "toml"
+
+	FormatJSONC = "jsonc"
+	FormatJSON5 = "json5"
+
+	FormatCSV = "csv"
+	FormatTSV = "tsv"
+	FormatINI = "ini"
+
+	FormatProperties = "properties"
+	FormatDotenv     = "dotenv"
+	FormatNDJSON     = "ndjson"
 )
 
 func init() {
-	Register(FormatJSON, json.Marshal, json.Unmarshal)
-	Register(FormatYaml, yaml.Marshal, yaml.Unmarshal)
-	Register(FormatXML, xml.Marshal, xml.Unmarshal)
-	Register(FormatTOML, toml.Marshal, toml.Unmarshal)
+	Register(FormatJSON, json.Marshal, json.Unmarshal,
+		WithExtensions(".json"),
+		WithMIMETypes("application/json", "text/json"),
+		WithMultiDocument(),
+		WithValidator(validateJSON(jsone.Strict)),
+	)
+	Register(FormatYaml, yaml.Marshal, yaml.Unmarshal,
+		WithExtensions(".yaml", ".yml"),
+		WithMIMETypes("application/yaml", "application/x-yaml", "text/yaml", "text/x-yaml"),
+		WithMultiDocument(),
+		WithValidator(validateYaml),
+	)
+	Register(FormatXML, xmle.Marshal, xmle.Unmarshal,
+		WithExtensions(".xml"),
+		WithMIMETypes("application/xml", "text/xml"),
+		WithValidator(validateXML),
+	)
+	Register(FormatTOML, toml.Marshal, toml.Unmarshal,
+		WithExtensions(".toml"),
+		WithMIMETypes("application/toml"),
+		WithValidator(validateTOML),
+	)
+	Register(FormatJSONC, json.Marshal, lenientUnmarshal(jsone.JSONC),
+		WithExtensions(".jsonc"),
+		WithMIMETypes("application/jsonc"),
+		WithMultiDocument(),
+		WithValidator(validateJSON(jsone.JSONC)),
+	)
+	Register(FormatJSON5, json.Marshal, lenientUnmarshal(jsone.JSON5),
+		WithExtensions(".json5"),
+		WithMIMETypes("application/json5"),
+		WithMultiDocument(),
+		WithValidator(validateJSON(jsone.JSON5)),
+	)
+	Register(FormatCSV, csve.Marshal, csve.Unmarshal,
+		WithExtensions(".csv"),
+		WithMIMETypes("text/csv"),
+	)
+	Register(FormatTSV, tsvMarshal, tsvUnmarshal,
+		WithExtensions(".tsv", ".tab"),
+		WithMIMETypes("text/tab-separated-values"),
+	)
+	Register(FormatINI, inie.Marshal, inie.Unmarshal,
+		WithExtensions(".ini", ".cfg"),
+	)
+	Register(FormatProperties, propertiese.Marshal, propertiese.Unmarshal,
+		WithExtensions(".properties"),
+		WithMIMETypes("text/x-java-properties"),
+	)
+	Register(FormatDotenv, dotenve.Marshal, dotenve.Unmarshal,
+		WithExtensions(".env"),
+	)
+	Register(FormatNDJSON, ndjsone.Marshal, ndjsone.Unmarshal,
+		WithExtensions(".ndjson", ".jsonl"),
+		WithMIMETypes("application/x-ndjson", "application/jsonl"),
+		WithMultiDocument(),
+		WithValidator(validateNDJSON),
+	)
 
 	RegisterED(FormatJSON, func(w io.Writer) Encoder {
 		return json.NewEncoder(w)
@@ -53,15 +125,47 @@ func init() {
 		return yaml.NewDecoder(r)
 	})
 	RegisterED(FormatXML, func(w io.Writer) Encoder {
-		return xml.NewEncoder(w)
+		return xmle.NewEncoder(w)
 	}, func(r io.Reader) Decoder {
-		return xml.NewDecoder(r)
+		return xmle.NewDecoder(r)
 	})
 	RegisterED(FormatTOML, func(w io.Writer) Encoder {
 		return toml.NewEncoder(w)
 	}, func(r io.Reader) Decoder {
 		return toml.NewDecoder(r)
 	})
+	RegisterED(FormatJSONC, func(w io.Writer) Encoder {
+		return json.NewEncoder(w)
+	}, newLenientDecoder(jsone.JSONC))
+	RegisterED(FormatJSON5, func(w io.Writer) Encoder {
+		return json.NewEncoder(w)
+	}, newLenientDecoder(jsone.JSON5))
+	RegisterED(FormatCSV, func(w io.Writer) Encoder {
+		return csve.NewEncoder(w)
+	}, func(r io.Reader) Decoder {
+		return csve.NewDecoder(r)
+	})
+	RegisterED(FormatTSV, newTSVEncoder, newTSVDecoder)
+	RegisterED(FormatINI, func(w io.Writer) Encoder {
+		return inie.NewEncoder(w)
+	}, func(r io.Reader) Decoder {
+		return inie.NewDecoder(r)
+	})
+	RegisterED(FormatProperties, func(w io.Writer) Encoder {
+		return propertiese.NewEncoder(w)
+	}, func(r io.Reader) Decoder {
+		return propertiese.NewDecoder(r)
+	})
+	RegisterED(FormatDotenv, func(w io.Writer) Encoder {
+		return dotenve.NewEncoder(w)
+	}, func(r io.Reader) Decoder {
+		return dotenve.NewDecoder(r)
+	})
+	RegisterED(FormatNDJSON, func(w io.Writer) Encoder {
+		return ndjsone.NewEncoder(w)
+	}, func(r io.Reader) Decoder {
+		return ndjsone.NewDecoder(r)
+	})
 }
 
 type (
@@ -82,104 +186,514 @@ type (
 )
 
 type Engine struct {
-	marshal   MarshalFunc
-	unmarshal UnmarshalFunc
-	eFunc     EncoderFunc
-	dFunc     DecoderFunc
+	name       string
+	marshal    MarshalFunc
+	unmarshal  UnmarshalFunc
+	eFunc      EncoderFunc
+	dFunc      DecoderFunc
+	validate   ValidateFunc
+	extensions []string
+	mimeTypes  []string
+	multiDoc   bool
 }
 
-func Register(name string, m MarshalFunc, um UnmarshalFunc) {
-	e, ok := set[name]
-	if !ok {
-		e = &Engine{}
-		set[name] = e
+// EngineOption configures an Engine when it is registered.
+type EngineOption func(e *Engine)
+
+// WithExtensions declares the file extensions handled by the engine, e.g. ".json".
+func WithExtensions(exts ...string) EngineOption {
+	return func(e *Engine) {
+		for _, ext := range exts {
+			ext = strings.ToLower(ext)
+			if !strings.HasPrefix(ext, ".") {
+				ext = "." + ext
+			}
+			e.extensions = append(e.extensions, ext)
+		}
+	}
+}
+
+// WithMIMETypes declares the MIME types handled by the engine, e.g. "application/json".
+func WithMIMETypes(types ...string) EngineOption {
+	return func(e *Engine) {
+		for _, typ := range types {
+			e.mimeTypes = append(e.mimeTypes, strings.ToLower(typ))
+		}
+	}
+}
+
+// Name returns the format name the engine is registered under.
+func (e *Engine) Name() string {
+	return e.name
+}
+
+// WithMultiDocument declares that the engine decoder yields successive documents
+// until io.EOF and that its encoder writes documents one after another,
+// e.g. YAML documents separated by "---" or a stream of JSON values.
+func WithMultiDocument() EngineOption {
+	return func(e *Engine) {
+		e.multiDoc = true
+	}
+}
+
+// Extensions returns the file extensions declared for the engine.
+func (e *Engine) Extensions() []string {
+	return append([]string(nil), e.extensions...)
+}
+
+// MIMETypes returns the MIME types declared for the engine.
+func (e *Engine) MIMETypes() []string {
+	return append([]string(nil), e.mimeTypes...)
+}
+
+func (e *Engine) Marshal(v interface{}) ([]byte, error) {
+	if e.marshal == nil {
+		return nil, &EngineError{Name: e.name, Direction: DirectionOutput, Err: ErrCodecUnsupported}
+	}
+	return e.marshal(v)
+}
+
+func (e *Engine) Unmarshal(data []byte, v interface{}) error {
+	if e.unmarshal == nil {
+		return &EngineError{Name: e.name, Direction: DirectionInput, Err: ErrCodecUnsupported}
+	}
+	return e.unmarshal(data, v)
+}
+
+func (e *Engine) NewEncoder(w io.Writer) (Encoder, error) {
+	if e.eFunc == nil {
+		return nil, &EngineError{Name: e.name, Direction: DirectionOutput, Err: ErrStreamingUnsupported}
+	}
+	return e.eFunc(w), nil
+}
+
+func (e *Engine) NewDecoder(r io.Reader) (Decoder, error) {
+	if e.dFunc == nil {
+		return nil, &EngineError{Name: e.name, Direction: DirectionInput, Err: ErrStreamingUnsupported}
+	}
+	return e.dFunc(r), nil
+}
+
+// Registry holds a set of named engines and is safe for concurrent use.
+type Registry struct {
+	mu      sync.RWMutex
+	engines map[string]*Engine
+}
+
+// NewRegistry returns an empty engine registry.
+func NewRegistry() *Registry {
+	return &Registry{engines: make(map[string]*Engine)}
+}
+
+var defaultRegistry = NewRegistry()
+
+// DefaultRegistry returns the registry used by the package-level functions.
+func DefaultRegistry() *Registry {
+	return defaultRegistry
+}
+
+// Register sets the marshal and unmarshal functions of the named engine.
+func (r *Registry) Register(name string, m MarshalFunc, um UnmarshalFunc, opts ...EngineOption) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// engines are copied on write, so readers never see a half-updated engine
+	e := &Engine{name: name}
+	if old, ok := r.engines[name]; ok {
+		*e = *old
 	}
 	e.marshal = m
 	e.unmarshal = um
+	// a validator checks the syntax of the unmarshal function it was registered with
+	e.validate = nil
+	for _, opt := range opts {
+		opt(e)
+	}
+	r.engines[name] = e
 }
 
-func RegisterED(name string, eFunc EncoderFunc, dFunc DecoderFunc) {
-	e, ok := set[name]
-	if !ok {
-		e = &Engine{}
-		set[name] = e
+// RegisterED sets the encoder and decoder functions of the named engine.
+func (r *Registry) RegisterED(name string, eFunc EncoderFunc, dFunc DecoderFunc, opts ...EngineOption) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e := &Engine{name: name}
+	if old, ok := r.engines[name]; ok {
+		*e = *old
 	}
 	e.eFunc = eFunc
 	e.dFunc = dFunc
+	for _, opt := range opts {
+		opt(e)
+	}
+	r.engines[name] = e
 }
 
-func Marshal(name string, v interface{}) ([]byte, error) {
-	e, ok := set[name]
-	if !ok {
-		return nil, fmt.Errorf("failed to find %s engine", name)
+// Get returns the named engine.
+func (r *Registry) Get(name string) (*Engine, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	e, ok := r.engines[name]
+	return e, ok
+}
+
+// Names returns the names of all registered engines.
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.engines))
+	for name := range r.engines {
+		names = append(names, name)
 	}
-	return e.marshal(v)
+	sort.Strings(names)
+	return names
 }
 
-func Unmarshal(name string, data []byte, v interface{}) error {
-	e, ok := set[name]
+func (r *Registry) lookup(name string, dir Direction) (*Engine, error) {
+	e, ok := r.Get(name)
 	if !ok {
-		return fmt.Errorf("failed to find %s engine", name)
+		return nil, &EngineError{Name: name, Direction: dir, Err: ErrEngineNotFound}
 	}
-	return e.unmarshal(data, v)
+	return e, nil
+}
+
+func (r *Registry) Marshal(name string, v interface{}) ([]byte, error) {
+	e, err := r.lookup(name, DirectionOutput)
+	if err != nil {
+		return nil, err
+	}
+	return e.Marshal(v)
 }
 
+func (r *Registry) Unmarshal(name string, data []byte, v interface{}) error {
+	e, err := r.lookup(name, DirectionInput)
+	if err != nil {
+		return err
+	}
+	return e.Unmarshal(data, v)
+}
+
+func Register(name string, m MarshalFunc, um UnmarshalFunc, opts ...EngineOption) {
+	defaultRegistry.Register(name, m, um, opts...)
+}
+
+func RegisterED(name string, eFunc EncoderFunc, dFunc DecoderFunc, opts ...EngineOption) {
+	defaultRegistry.RegisterED(name, eFunc, dFunc, opts...)
+}
+
+func Marshal(name string, v interface{}) ([]byte, error) {
+	return defaultRegistry.Marshal(name, v)
+}
+
+func Unmarshal(name string, data []byte, v interface{}) error {
+	return defaultRegistry.Unmarshal(name, data, v)
+}
+
+// DefaultDocumentsKey is the key wrapping multiple documents
+// for output formats that hold a single document, other than CSV and TSV.
+const DefaultDocumentsKey = "documents"
+
 type Transfer struct {
-	in  string
-	out string
+	in           string
+	out          string
+	registry     *Registry
+	documentsKey string
+	ordered      bool
+	xmlOptions   xmle.Options
+	xmlRecords   string
+	csvOptions   csve.Options
+	envOptions   dotenve.Options
 }
 
-func NewTransfer(in, out string) *Transfer {
-	return &Transfer{in: in, out: out}
+// TransferOption configures a Transfer.
+type TransferOption func(t *Transfer)
+
+// WithRegistry makes the Transfer look up its engines in r
+// instead of the default registry.
+func WithRegistry(r *Registry) TransferOption {
+	return func(t *Transfer) {
+		t.registry = r
+	}
+}
+
+// WithDocumentsKey sets the key of the array wrapping multiple documents
+// when the output format cannot hold several of them, such as TOML and XML.
+func WithDocumentsKey(key string) TransferOption {
+	return func(t *Transfer) {
+		t.documentsKey = key
+	}
+}
+
+// WithOrderedKeys keeps the source order of keys through the conversion
+// instead of sorting them alphabetically.
+func WithOrderedKeys() TransferOption {
+	return func(t *Transfer) {
+		t.ordered = true
+	}
 }
 
+// WithXMLOptions sets the options used to decode and encode XML documents.
+func WithXMLOptions(opts xmle.Options) TransferOption {
+	return func(t *Transfer) {
+		t.xmlOptions = opts
+	}
+}
+
+// WithXMLRecords makes the Transfer read the elements at path of an XML input,
+// such as /catalog/book, as separate documents streamed one by one.
+func WithXMLRecords(path string) TransferOption {
+	return func(t *Transfer) {
+		t.xmlRecords = path
+	}
+}
+
+// WithCSVOptions sets the options used to decode and encode CSV and TSV tables,
+// whose fields are separated by commas and tabs respectively unless opts set the delimiter.
+func WithCSVOptions(opts csve.Options) TransferOption {
+	return func(t *Transfer) {
+		t.csvOptions = opts
+	}
+}
+
+// WithDotenvOptions sets the options used to decode and encode env files.
+func WithDotenvOptions(opts dotenve.Options) TransferOption {
+	return func(t *Transfer) {
+		t.envOptions = opts
+	}
+}
+
+func NewTransfer(in, out string, opts ...TransferOption) *Transfer {
+	t := &Transfer{in: in, out: out}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+func (t *Transfer) engines() *Registry {
+	if t.registry == nil {
+		return defaultRegistry
+	}
+	return t.registry
+}
+
+// Exchange converts data and returns the result.
+// Every document of a multi-document input is converted as by ExchangeED,
+// a single document is marshaled as is.
 func (t *Transfer) Exchange(data []byte) ([]byte, error) {
-	ipr, ok := set[t.in]
-	if !ok {
-		return nil, errors.New("failed to find input engine")
+	ipr, err := t.engines().lookup(t.in, DirectionInput)
+	if err != nil {
+		return nil, err
 	}
-	opr, ok := set[t.out]
-	if !ok {
-		return nil, errors.New("failed to find output engine")
+	opr, err := t.engines().lookup(t.out, DirectionOutput)
+	if err != nil {
+		return nil, err
 	}
-	var spec interface{}
-	if t.in == FormatXML {
-		xmlSpec := make(xmle.Map)
-		if err := ipr.unmarshal(data, &xmlSpec); err != nil {
+	if (ipr.multiDoc && ipr.dFunc != nil) || t.records() {
+		return t.exchangeDocuments(ipr, opr, data)
+	}
+	spec, err := t.decode(func(v interface{}) error {
+		return ipr.Unmarshal(data, v)
+	}, func() []byte {
+		return data
+	})
+	if err != nil {
+		return nil, err
+	}
+	return opr.Marshal(t.encodable(spec))
+}
+
+// exchangeDocuments converts every document of data.
+func (t *Transfer) exchangeDocuments(ipr, opr *Engine, data []byte) ([]byte, error) {
+	dec, err := t.newDecoder(ipr, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	var docs []interface{}
+	err = t.eachDocument(dec, true, func() []byte {
+		return data
+	}, func(spec interface{}) error {
+		docs = append(docs, spec)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case len(docs) == 1:
+		return opr.Marshal(t.encodable(docs[0]))
+	case opr.multiDoc && opr.eFunc != nil:
+		var buf bytes.Buffer
+		enc, err := opr.NewEncoder(&buf)
+		if err != nil {
 			return nil, err
 		}
-		spec = xmlSpec
-	} else {
-		if err := ipr.unmarshal(data, &spec); err != nil {
-			return nil, err
+		for _, spec := range docs {
+			if err := enc.Encode(t.encodable(spec)); err != nil {
+				return nil, err
+			}
 		}
+		return buf.Bytes(), nil
+	case len(docs) == 0:
+		return nil, nil
 	}
-	if err := transformData(&spec); err != nil {
-		return nil, err
+	return opr.Marshal(t.encodable(t.wrapDocuments(docs)))
+}
+
+// ExchangeED converts every document read from r and writes them to w.
+// Documents are streamed one by one when the output engine supports multiple documents,
+// otherwise they are wrapped in an array under the documents key,
+// or written as the records of CSV and TSV.
+func (t *Transfer) ExchangeED(r io.Reader, w io.Writer) error {
+	iParser, err := t.engines().lookup(t.in, DirectionInput)
+	if err != nil {
+		return err
 	}
-	if t.out == FormatXML {
-		spec = xmle.Map(spec.(map[string]interface{}))
+	oParser, err := t.engines().lookup(t.out, DirectionOutput)
+	if err != nil {
+		return err
+	}
+	// keep the raw document to find the key order of TOML
+	var raw bytes.Buffer
+	if t.ordered && t.in == FormatTOML {
+		r = io.TeeReader(r, &raw)
 	}
-	return opr.marshal(spec)
+	dec, err := t.newDecoder(iParser, r)
+	if err != nil {
+		return err
+	}
+	enc, err := oParser.NewEncoder(w)
+	if err != nil {
+		return err
+	}
+
+	var docs []interface{}
+	err = t.eachDocument(dec, iParser.multiDoc || t.records(), raw.Bytes, func(spec interface{}) error {
+		if oParser.multiDoc {
+			return enc.Encode(t.encodable(spec))
+		}
+		docs = append(docs, spec)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	switch len(docs) {
+	case 0:
+		return nil
+	case 1:
+		return enc.Encode(t.encodable(docs[0]))
+	}
+	return enc.Encode(t.encodable(t.wrapDocuments(docs)))
 }
 
-func (t *Transfer) ExchangeED(r io.Reader, w io.Writer) error {
-	iParser, ok := set[t.in]
-	if !ok {
-		return errors.New("failed to find reader engine")
+// newDecoder returns the decoder of the input engine, or of the XML records.
+func (t *Transfer) newDecoder(e *Engine, r io.Reader) (Decoder, error) {
+	if t.records() {
+		return xmle.NewRecordDecoder(r, t.xmlRecords, t.xmlOptions), nil
 	}
-	oParser, ok := set[t.out]
-	if !ok {
-		return errors.New("failed to find writer engine")
+	return e.NewDecoder(r)
+}
+
+// eachDocument calls fn with every document read by dec,
+// or with the first one only unless multiDoc is set.
+func (t *Transfer) eachDocument(dec Decoder, multiDoc bool, raw func() []byte, fn func(spec interface{}) error) error {
+	for {
+		spec, err := t.decode(dec.Decode, raw)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := fn(spec); err != nil {
+			return err
+		}
+		if !multiDoc {
+			return nil
+		}
 	}
+}
+
+// wrapDocuments wraps several documents in an array under the documents key,
+// or returns the array as is for CSV and TSV, which write every document as a record.
+func (t *Transfer) wrapDocuments(docs []interface{}) interface{} {
+	if t.out == FormatCSV || t.out == FormatTSV {
+		return docs
+	}
+	key := t.documentsKey
+	if key == "" {
+		key = DefaultDocumentsKey
+	}
+	return map[string]interface{}{key: docs}
+}
+
+// records reports whether the documents are the records of an XML input.
+func (t *Transfer) records() bool {
+	return t.in == FormatXML && t.xmlRecords != ""
+}
+
+// decode reads a document with the decode function of the input engine
+// and normalizes it to plain maps, or ordered maps when keys are ordered, and slices.
+// raw returns the document read so far.
+func (t *Transfer) decode(decode func(v interface{}) error, raw func() []byte) (interface{}, error) {
 	var spec interface{}
-	if err := iParser.dFunc(r).Decode(&spec); err != nil {
-		return err
+	switch {
+	case t.in == FormatXML:
+		doc := &xmle.Document{Options: t.xmlOptions, Ordered: t.ordered}
+		if err := decode(doc); err != nil {
+			return nil, err
+		}
+		spec = doc.Value
+	case t.in == FormatCSV || t.in == FormatTSV:
+		doc := &csve.Document{Options: t.csvOptions, Ordered: t.ordered}
+		if err := decode(doc); err != nil {
+			return nil, err
+		}
+		spec = doc.Value
+	case t.in == FormatDotenv:
+		doc := &dotenve.Document{Options: t.envOptions, Ordered: t.ordered}
+		if err := decode(doc); err != nil {
+			return nil, err
+		}
+		spec = doc.Value
+	case t.in == FormatTOML && t.ordered:
+		var tomlSpec map[string]interface{}
+		if err := decode(&tomlSpec); err != nil {
+			return nil, err
+		}
+		spec = orderTOML(raw(), tomlSpec)
+	case orderedFormats[t.in] && t.ordered:
+		var value ordered.Value
+		if err := decode(&value); err != nil {
+			return nil, err
+		}
+		spec = value.V
+	default:
+		if err := decode(&spec); err != nil {
+			return nil, err
+		}
 	}
 	if err := transformData(&spec); err != nil {
-		return err
+		return nil, err
 	}
-	return oParser.eFunc(w).Encode(&spec)
+	return spec, nil
+}
+
+// encodable adapts a normalized document to the output engine.
+func (t *Transfer) encodable(spec interface{}) interface{} {
+	switch t.out {
+	case FormatXML:
+		switch spec.(type) {
+		case map[string]interface{}, ordered.Map:
+			return xmle.Document{Options: t.xmlOptions, Value: spec}
+		}
+	case FormatTOML:
+		return tomlValue(spec)
+	case FormatCSV, FormatTSV:
+		return csve.Document{Options: t.csvOptions, Value: spec}
+	case FormatDotenv:
+		return dotenve.Document{Options: t.envOptions, Value: spec}
+	}
+	return spec
 }
 
 func transformData(pIn *interface{}) (err error) {
@@ -208,6 +722,12 @@ func transformData(pIn *interface{}) (err error) {
 			m[sk] = v
 		}
 		*pIn = m
+	case ordered.Map:
+		for i := range in {
+			if err = transformData(&in[i].Value); err != nil {
+				return err
+			}
+		}
 	case []interface{}:
 		for i := len(in) - 1; i >= 0; i-- {
 			if err = transformData(&in[i]); err != nil {
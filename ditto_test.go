@@ -16,12 +16,20 @@ package ditto
 
 import (
 	"bytes"
+	"encoding/json"
+	"errors"
 	"io"
 	"reflect"
 	"strings"
+	"sync"
 	"testing"
 
+	csve "github.com/99nil/ditto/csv"
+	dotenve "github.com/99nil/ditto/dotenv"
+	jsone "github.com/99nil/ditto/json"
+	xmle "github.com/99nil/ditto/xml"
 	jsoniter "github.com/json-iterator/go"
+	"github.com/pelletier/go-toml/v2"
 )
 
 func TestNewTransfer(t *testing.T) {
@@ -124,6 +132,114 @@ func TestRegisterED(t *testing.T) {
 	}
 }
 
+func TestRegistry(t *testing.T) {
+	r := NewRegistry()
+	r.Register("isolated", json.Marshal, json.Unmarshal)
+	if _, ok := DefaultRegistry().Get("isolated"); ok {
+		t.Fatal("engine leaked into the default registry")
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			r.RegisterED("isolated", func(w io.Writer) Encoder {
+				return json.NewEncoder(w)
+			}, func(r io.Reader) Decoder {
+				return json.NewDecoder(r)
+			})
+		}()
+		go func() {
+			defer wg.Done()
+			if _, err := r.Marshal("isolated", map[string]int{"a": 1}); err != nil {
+				t.Errorf("Marshal() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	got, err := NewTransfer("isolated", "isolated", WithRegistry(r)).Exchange([]byte(`{"a":1}`))
+	if err != nil {
+		t.Fatalf("Exchange() error = %v", err)
+	}
+	if string(got) != `{"a":1}` {
+		t.Errorf("Exchange() got = %s, want %s", got, `{"a":1}`)
+	}
+	if _, err := NewTransfer(FormatJSON, FormatYaml, WithRegistry(r)).Exchange([]byte(jsonStr)); err == nil {
+		t.Error("Exchange() expected error for engine missing from registry")
+	}
+}
+
+func TestEngineErrors(t *testing.T) {
+	r := NewRegistry()
+	r.Register("codec", json.Marshal, json.Unmarshal)
+	r.RegisterED("stream", func(w io.Writer) Encoder {
+		return json.NewEncoder(w)
+	}, func(r io.Reader) Decoder {
+		return json.NewDecoder(r)
+	})
+
+	tests := []struct {
+		name    string
+		fn      func() error
+		wantErr error
+		wantDir Direction
+	}{
+		{
+			name: "marshal-not-found",
+			fn: func() error {
+				_, err := r.Marshal("missing", nil)
+				return err
+			},
+			wantErr: ErrEngineNotFound,
+			wantDir: DirectionOutput,
+		},
+		{
+			name: "exchange-input-not-found",
+			fn: func() error {
+				_, err := NewTransfer("missing", "codec", WithRegistry(r)).Exchange([]byte(`{}`))
+				return err
+			},
+			wantErr: ErrEngineNotFound,
+			wantDir: DirectionInput,
+		},
+		{
+			name: "exchange-codec-unsupported",
+			fn: func() error {
+				_, err := NewTransfer("stream", "codec", WithRegistry(r)).Exchange([]byte(`{}`))
+				return err
+			},
+			wantErr: ErrCodecUnsupported,
+			wantDir: DirectionInput,
+		},
+		{
+			name: "exchangeED-streaming-unsupported",
+			fn: func() error {
+				return NewTransfer("stream", "codec", WithRegistry(r)).
+					ExchangeED(strings.NewReader(`{}`), &bytes.Buffer{})
+			},
+			wantErr: ErrStreamingUnsupported,
+			wantDir: DirectionOutput,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.fn()
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			var ee *EngineError
+			if !errors.As(err, &ee) {
+				t.Fatalf("error = %T, want *EngineError", err)
+			}
+			if ee.Direction != tt.wantDir {
+				t.Errorf("Direction = %v, want %v", ee.Direction, tt.wantDir)
+			}
+		})
+	}
+}
+
 const (
 	jsonStr = `{"database":{"connection_max":5000,"ports":[8001,8002,8003],"server":"127.0.0.1"},"owner":{"name":"zc"},"title":"demo"}`
 
@@ -166,6 +282,27 @@ name = 'zc'
 	<title>demo</title>
 </xml>
 `
+	jsoncStr = `{
+	// connection settings
+	"database": {
+		"connection_max": 5000,
+		"ports": [8001, 8002, 8003,],
+		"server": "127.0.0.1", /* local only */
+	},
+	"owner": {"name": "zc"},
+	"title": "demo",
+}`
+
+	json5Str = `{
+	database: {
+		connection_max: 5000,
+		ports: [8001, 8002, 8003],
+		server: '127.0.0.1',
+	},
+	owner: {name: 'zc'},
+	title: 'demo',
+}`
+
 	yamlStr2 = `
 database:
   connection_max: "5000"
@@ -255,6 +392,30 @@ func TestTransfer_Exchange(t1 *testing.T) {
 			want:    []byte(xmlStr),
 			wantErr: false,
 		},
+		{
+			name: "jsonc-to-json",
+			fields: fields{
+				in:  FormatJSONC,
+				out: FormatJSON,
+			},
+			args: args{
+				data: []byte(jsoncStr),
+			},
+			want:    []byte(jsonStr),
+			wantErr: false,
+		},
+		{
+			name: "json5-to-yaml",
+			fields: fields{
+				in:  FormatJSON5,
+				out: FormatYaml,
+			},
+			args: args{
+				data: []byte(json5Str),
+			},
+			want:    []byte(yamlStr),
+			wantErr: false,
+		},
 		{
 			name: "xml-to-yaml",
 			fields: fields{
@@ -288,6 +449,533 @@ func TestTransfer_Exchange(t1 *testing.T) {
 	}
 }
 
+func TestTransfer_ExchangeDocuments(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		out  string
+		data string
+		want string
+	}{
+		{
+			name: "yaml-to-json",
+			in:   FormatYaml,
+			out:  FormatJSON,
+			data: "kind: A\n---\nkind: B\n",
+			want: "{\"kind\":\"A\"}\n{\"kind\":\"B\"}\n",
+		},
+		{
+			name: "json-stream-to-yaml",
+			in:   FormatJSON,
+			out:  FormatYaml,
+			data: `{"kind":"A"} {"kind":"B"}`,
+			want: "kind: A\n---\nkind: B\n",
+		},
+		{
+			name: "yaml-to-toml",
+			in:   FormatYaml,
+			out:  FormatTOML,
+			data: "kind: A\n---\nkind: B\n",
+			want: "[[documents]]\nkind = 'A'\n[[documents]]\nkind = 'B'\n\n",
+		},
+		{
+			name: "single-document",
+			in:   FormatYaml,
+			out:  FormatJSON,
+			data: "kind: A\n",
+			want: `{"kind":"A"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewTransfer(tt.in, tt.out).Exchange([]byte(tt.data))
+			if err != nil {
+				t.Fatalf("Exchange() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Exchange() got = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithDocumentsKey(t *testing.T) {
+	w := &bytes.Buffer{}
+	err := NewTransfer(FormatYaml, FormatXML, WithDocumentsKey("item")).
+		ExchangeED(strings.NewReader("a: 1\n---\nb: 2\n"), w)
+	if err != nil {
+		t.Fatalf("ExchangeED() error = %v", err)
+	}
+	want := `<xml>
+    <item type="array">
+        <a>1</a>
+    </item>
+    <item type="array">
+        <b>2</b>
+    </item>
+</xml>`
+	if w.String() != want {
+		t.Errorf("ExchangeED() gotW = %v, want %v", w, want)
+	}
+}
+
+const (
+	orderedJSONStr = `{"title":"demo","owner":{"name":"zc","age":3},"database":{"server":"127.0.0.1","ports":[8001,8002],"connection_max":5000}}`
+
+	orderedYamlStr = `
+title: demo
+owner:
+  name: zc
+  age: 3
+database:
+  server: 127.0.0.1
+  ports:
+  - 8001
+  - 8002
+  connection_max: 5000
+`
+
+	orderedTOMLStr = `
+title = 'demo'
+[owner]
+name = 'zc'
+age = 3
+
+[database]
+server = '127.0.0.1'
+ports = [8001, 8002]
+connection_max = 5000
+`
+
+	orderedXMLStr = `
+<xml>
+    <title>demo</title>
+    <owner>
+        <name>zc</name>
+        <age>3</age>
+    </owner>
+    <database>
+        <server>127.0.0.1</server>
+        <ports type="array">8001</ports>
+        <ports type="array">8002</ports>
+        <connection_max>5000</connection_max>
+    </database>
+</xml>
+`
+)
+
+func TestTransfer_ExchangeOrdered(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		out  string
+		data string
+		want string
+	}{
+		{name: "json-to-yaml", in: FormatJSON, out: FormatYaml, data: orderedJSONStr, want: orderedYamlStr},
+		{name: "yaml-to-toml", in: FormatYaml, out: FormatTOML, data: orderedYamlStr, want: orderedTOMLStr},
+		{name: "toml-to-json", in: FormatTOML, out: FormatJSON, data: orderedTOMLStr, want: orderedJSONStr},
+		{name: "toml-to-xml", in: FormatTOML, out: FormatXML, data: orderedTOMLStr, want: orderedXMLStr},
+		{name: "xml-to-xml", in: FormatXML, out: FormatXML, data: orderedXMLStr, want: orderedXMLStr},
+		{
+			name: "multi-yaml-to-toml",
+			in:   FormatYaml,
+			out:  FormatTOML,
+			data: "b: 1\na: 2\n---\nd: 3\nc: 4\n",
+			want: "[[documents]]\nb = 1\na = 2\n[[documents]]\nd = 3\nc = 4",
+		},
+		{
+			name: "dash-key-to-toml",
+			in:   FormatJSON,
+			out:  FormatTOML,
+			data: `{"-":2,"b":3}`,
+			want: "- = 2\nb = 3",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewTransfer(tt.in, tt.out, WithOrderedKeys()).Exchange([]byte(tt.data))
+			if err != nil {
+				t.Fatalf("Exchange() error = %v", err)
+			}
+			if !bytes.Equal(UnifiedTreatment(got), UnifiedTreatment([]byte(tt.want))) {
+				t.Errorf("Exchange() got = %s\n want = %s\n", got, tt.want)
+			}
+		})
+	}
+
+	w := &bytes.Buffer{}
+	err := NewTransfer(FormatTOML, FormatJSON, WithOrderedKeys()).ExchangeED(strings.NewReader(orderedTOMLStr), w)
+	if err != nil {
+		t.Fatalf("ExchangeED() error = %v", err)
+	}
+	if got := strings.TrimSuffix(w.String(), "\n"); got != orderedJSONStr {
+		t.Errorf("ExchangeED() gotW = %v, want %v", got, orderedJSONStr)
+	}
+}
+
+func TestWithXMLOptions(t *testing.T) {
+	got, err := NewTransfer(FormatXML, FormatJSON, WithXMLOptions(xmle.Options{InferTypes: true})).
+		Exchange([]byte(xmlStr))
+	if err != nil {
+		t.Fatalf("Exchange() error = %v", err)
+	}
+	if string(got) != jsonStr {
+		t.Errorf("Exchange() got = %s, want %s", got, jsonStr)
+	}
+}
+
+func TestWithXMLOptions_Attributes(t *testing.T) {
+	const src = `<xml>
+    <a id="1">x</a>
+</xml>`
+	opt := WithXMLOptions(xmle.Options{AttrPrefix: xmle.DefaultAttrPrefix})
+	js, err := NewTransfer(FormatXML, FormatJSON, opt).Exchange([]byte(src))
+	if err != nil {
+		t.Fatalf("Exchange() error = %v", err)
+	}
+	const wantJSON = `{"a":{"#text":"x","@id":"1"}}`
+	if string(js) != wantJSON {
+		t.Errorf("Exchange() got = %s, want %s", js, wantJSON)
+	}
+	got, err := NewTransfer(FormatJSON, FormatXML, opt).Exchange(js)
+	if err != nil {
+		t.Fatalf("Exchange() error = %v", err)
+	}
+	if string(got) != src {
+		t.Errorf("Exchange() got = %s, want %s", got, src)
+	}
+}
+
+func TestTransfer_ExchangeNestedArrays(t *testing.T) {
+	const src = `{"matrix":[[1,2],[3,4]]}`
+	opt := WithXMLOptions(xmle.Options{InferTypes: true})
+	data, err := NewTransfer(FormatJSON, FormatXML, opt).Exchange([]byte(src))
+	if err != nil {
+		t.Fatalf("Exchange() error = %v", err)
+	}
+	got, err := NewTransfer(FormatXML, FormatJSON, opt).Exchange(data)
+	if err != nil {
+		t.Fatalf("Exchange() error = %v", err)
+	}
+	if string(got) != src {
+		t.Errorf("Exchange() got = %s, want %s", got, src)
+	}
+}
+
+func TestWithXMLOptions_Encoding(t *testing.T) {
+	opts := xmle.Options{Root: "config", Indent: "  ", Declaration: true, SelfClosing: true}
+	want := `<?xml version="1.0" encoding="UTF-8"?>
+<config>
+  <empty/>
+  <name>zc</name>
+</config>`
+	got, err := NewTransfer(FormatJSON, FormatXML, WithXMLOptions(opts)).
+		Exchange([]byte(`{"name":"zc","empty":""}`))
+	if err != nil {
+		t.Fatalf("Exchange() error = %v", err)
+	}
+	if string(got) != want {
+		t.Errorf("Exchange() got = %s, want %s", got, want)
+	}
+
+	var buf bytes.Buffer
+	err = NewTransfer(FormatJSON, FormatXML, WithXMLOptions(opts)).
+		ExchangeED(strings.NewReader(`{"name":"zc","empty":""}`), &buf)
+	if err != nil {
+		t.Fatalf("ExchangeED() error = %v", err)
+	}
+	if buf.String() != want {
+		t.Errorf("ExchangeED() got = %s, want %s", buf.String(), want)
+	}
+}
+
+func TestWithXMLOptions_Markup(t *testing.T) {
+	const src = `<xml>
+    <!-- note -->
+    <script><![CDATA[a < b]]></script>
+</xml>`
+	opt := WithXMLOptions(xmle.Options{Markup: true})
+	js, err := NewTransfer(FormatXML, FormatJSON, opt).Exchange([]byte(src))
+	if err != nil {
+		t.Fatalf("Exchange() error = %v", err)
+	}
+	const wantJSON = `{"#comment":" note ","script":{"#cdata":"a \u003c b"}}`
+	if string(js) != wantJSON {
+		t.Errorf("Exchange() got = %s, want %s", js, wantJSON)
+	}
+	var buf bytes.Buffer
+	if err := NewTransfer(FormatJSON, FormatXML, opt).ExchangeED(bytes.NewReader(js), &buf); err != nil {
+		t.Fatalf("ExchangeED() error = %v", err)
+	}
+	if buf.String() != src {
+		t.Errorf("ExchangeED() got = %s, want %s", buf.String(), src)
+	}
+}
+
+func TestWithXMLRecords(t *testing.T) {
+	const src = `<catalog>
+    <book><title>Go</title></book>
+    <book><title>XML</title></book>
+</catalog>`
+	want := "{\"title\":\"Go\"}\n{\"title\":\"XML\"}\n"
+	var buf bytes.Buffer
+	err := NewTransfer(FormatXML, FormatJSON, WithXMLRecords("/catalog/book")).
+		ExchangeED(strings.NewReader(src), &buf)
+	if err != nil {
+		t.Fatalf("ExchangeED() error = %v", err)
+	}
+	if buf.String() != want {
+		t.Errorf("ExchangeED() got = %s, want %s", buf.String(), want)
+	}
+
+	got, err := NewTransfer(FormatXML, FormatTOML, WithXMLRecords("/catalog/book")).Exchange([]byte(src))
+	if err != nil {
+		t.Fatalf("Exchange() error = %v", err)
+	}
+	var v map[string]interface{}
+	if err := toml.Unmarshal(got, &v); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if docs, _ := v[DefaultDocumentsKey].([]interface{}); len(docs) != 2 {
+		t.Errorf("Exchange() got = %s, want 2 documents", got)
+	}
+}
+
+func TestTransfer_CSV(t *testing.T) {
+	const table = "name,owner.name,stars\nditto,zc,12\nyaml,go,3\n"
+	const records = `[{"name":"ditto","owner":{"name":"zc"},"stars":12},{"name":"yaml","owner":{"name":"go"},"stars":3}]`
+	opts := WithCSVOptions(csve.Options{InferTypes: true})
+
+	got, err := NewTransfer(FormatCSV, FormatJSON, opts).Exchange([]byte(table))
+	if err != nil {
+		t.Fatalf("Exchange() error = %v", err)
+	}
+	if string(got) != records {
+		t.Errorf("Exchange() got = %s, want %s", got, records)
+	}
+
+	got, err = NewTransfer(FormatJSON, FormatCSV).Exchange([]byte(records))
+	if err != nil {
+		t.Fatalf("Exchange() error = %v", err)
+	}
+	if string(got) != table {
+		t.Errorf("Exchange() got = %q, want %q", got, table)
+	}
+
+	var buf bytes.Buffer
+	tsv := strings.ReplaceAll(table, ",", "\t")
+	err = NewTransfer(FormatTSV, FormatJSON, opts).ExchangeED(strings.NewReader(tsv), &buf)
+	if err != nil {
+		t.Fatalf("ExchangeED() error = %v", err)
+	}
+	if buf.String() != records+"\n" {
+		t.Errorf("ExchangeED() got = %s, want %s", buf.String(), records)
+	}
+
+	buf.Reset()
+	err = NewTransfer(FormatJSON, FormatTSV, WithOrderedKeys()).ExchangeED(strings.NewReader(records), &buf)
+	if err != nil {
+		t.Fatalf("ExchangeED() error = %v", err)
+	}
+	if buf.String() != tsv {
+		t.Errorf("ExchangeED() got = %q, want %q", buf.String(), tsv)
+	}
+
+	// every document of a multi-document input is a record
+	lines := `{"name":"ditto","owner":{"name":"zc"},"stars":12}` + "\n" + `{"name":"yaml","owner":{"name":"go"},"stars":3}` + "\n"
+	got, err = NewTransfer(FormatNDJSON, FormatCSV).Exchange([]byte(lines))
+	if err != nil {
+		t.Fatalf("Exchange() error = %v", err)
+	}
+	if string(got) != table {
+		t.Errorf("Exchange() got = %q, want %q", got, table)
+	}
+
+	buf.Reset()
+	err = NewTransfer(FormatNDJSON, FormatCSV).ExchangeED(strings.NewReader(lines), &buf)
+	if err != nil {
+		t.Fatalf("ExchangeED() error = %v", err)
+	}
+	if buf.String() != table {
+		t.Errorf("ExchangeED() got = %q, want %q", buf.String(), table)
+	}
+}
+
+func TestTransfer_INI(t *testing.T) {
+	const src = `title = demo
+
+[owner]
+name = zc
+
+[database.replica]
+server = 10.0.0.2
+ports = 8001
+ports = 8002
+`
+	got, err := NewTransfer("ini", "toml").Exchange([]byte(src))
+	if err != nil {
+		t.Fatalf("Exchange() error = %v", err)
+	}
+	var v map[string]interface{}
+	if err := toml.Unmarshal(got, &v); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	want := map[string]interface{}{
+		"title": "demo",
+		"owner": map[string]interface{}{"name": "zc"},
+		"database": map[string]interface{}{
+			"replica": map[string]interface{}{
+				"server": "10.0.0.2",
+				"ports":  []interface{}{"8001", "8002"},
+			},
+		},
+	}
+	if !reflect.DeepEqual(v, want) {
+		t.Errorf("Exchange() got = %s, want %v", got, want)
+	}
+
+	var buf bytes.Buffer
+	err = NewTransfer(FormatINI, FormatINI, WithOrderedKeys()).ExchangeED(strings.NewReader(src), &buf)
+	if err != nil {
+		t.Fatalf("ExchangeED() error = %v", err)
+	}
+	if buf.String() != src {
+		t.Errorf("ExchangeED() got = %s, want %s", buf.String(), src)
+	}
+}
+
+func TestTransfer_Properties(t *testing.T) {
+	const src = `server.port=8080
+spring.datasource.url=jdbc:h2:mem:test
+app.servers[0]=a
+app.servers[1]=b
+`
+	const want = `server:
+  port: "8080"
+spring:
+  datasource:
+    url: jdbc:h2:mem:test
+app:
+  servers:
+  - a
+  - b
+`
+	got, err := NewTransfer(FormatProperties, FormatYaml, WithOrderedKeys()).Exchange([]byte(src))
+	if err != nil {
+		t.Fatalf("Exchange() error = %v", err)
+	}
+	if string(got) != want {
+		t.Errorf("Exchange() got = %s, want %s", got, want)
+	}
+
+	var buf bytes.Buffer
+	err = NewTransfer(FormatYaml, FormatProperties, WithOrderedKeys()).ExchangeED(strings.NewReader(want), &buf)
+	if err != nil {
+		t.Fatalf("ExchangeED() error = %v", err)
+	}
+	if buf.String() != src {
+		t.Errorf("ExchangeED() got = %s, want %s", buf.String(), src)
+	}
+}
+
+func TestTransfer_Dotenv(t *testing.T) {
+	const env = `DATABASE__CONNECTION_MAX=5000
+DATABASE__PORTS__0=8001
+DATABASE__PORTS__1=8002
+DATABASE__PORTS__2=8003
+DATABASE__SERVER=127.0.0.1
+OWNER__NAME=zc
+TITLE=demo
+`
+	opts := dotenve.Options{Separator: "__", Unflatten: true}
+	got, err := NewTransfer(FormatYaml, FormatDotenv, WithDotenvOptions(opts)).Exchange([]byte(yamlStr))
+	if err != nil {
+		t.Fatalf("Exchange() error = %v", err)
+	}
+	if string(got) != env {
+		t.Errorf("Exchange() got = %s, want %s", got, env)
+	}
+
+	var buf bytes.Buffer
+	err = NewTransfer(FormatDotenv, FormatYaml, WithDotenvOptions(opts)).ExchangeED(strings.NewReader(env), &buf)
+	if err != nil {
+		t.Fatalf("ExchangeED() error = %v", err)
+	}
+	if !bytes.Equal(UnifiedTreatment(buf.Bytes()), UnifiedTreatment([]byte(yamlStr2))) {
+		t.Errorf("ExchangeED() got = %s, want %s", buf.String(), yamlStr2)
+	}
+}
+
+func TestTransfer_NDJSON(t *testing.T) {
+	const logs = `{"level":"info","msg":"start","ms":0}
+
+{"level":"warn","msg":"slow","ms":1200}
+`
+	var buf bytes.Buffer
+	err := NewTransfer(FormatNDJSON, FormatNDJSON, WithOrderedKeys()).ExchangeED(strings.NewReader(logs), &buf)
+	if err != nil {
+		t.Fatalf("ExchangeED() error = %v", err)
+	}
+	want := "{\"level\":\"info\",\"msg\":\"start\",\"ms\":0}\n{\"level\":\"warn\",\"msg\":\"slow\",\"ms\":1200}\n"
+	if buf.String() != want {
+		t.Errorf("ExchangeED() got = %s, want %s", buf.String(), want)
+	}
+
+	buf.Reset()
+	err = NewTransfer(FormatYaml, FormatNDJSON).ExchangeED(strings.NewReader("a: 1\n---\nb: [x]\n"), &buf)
+	if err != nil {
+		t.Fatalf("ExchangeED() error = %v", err)
+	}
+	if want := "{\"a\":1}\n{\"b\":[\"x\"]}\n"; buf.String() != want {
+		t.Errorf("ExchangeED() got = %s, want %s", buf.String(), want)
+	}
+
+	// a top-level array is written one member per line by Exchange and ExchangeED alike
+	const array = `[{"a":1},{"b":["x"]}]`
+	buf.Reset()
+	err = NewTransfer(FormatJSON, FormatNDJSON).ExchangeED(strings.NewReader(array), &buf)
+	if err != nil {
+		t.Fatalf("ExchangeED() error = %v", err)
+	}
+	if want := "{\"a\":1}\n{\"b\":[\"x\"]}\n"; buf.String() != want {
+		t.Errorf("ExchangeED() got = %s, want %s", buf.String(), want)
+	}
+	got, err := NewTransfer(FormatJSON, FormatNDJSON).Exchange([]byte(array))
+	if err != nil {
+		t.Fatalf("Exchange() error = %v", err)
+	}
+	if string(got) != buf.String() {
+		t.Errorf("Exchange() got = %s, want %s", got, buf.String())
+	}
+
+	buf.Reset()
+	err = NewTransfer(FormatCSV, FormatNDJSON).ExchangeED(strings.NewReader("name,stars\nditto,12\n"), &buf)
+	if err != nil {
+		t.Fatalf("ExchangeED() error = %v", err)
+	}
+	if want := "{\"name\":\"ditto\",\"stars\":\"12\"}\n"; buf.String() != want {
+		t.Errorf("ExchangeED() got = %s, want %s", buf.String(), want)
+	}
+
+	got, err = NewTransfer(FormatNDJSON, FormatJSON).Exchange([]byte(logs))
+	if err != nil {
+		t.Fatalf("Exchange() error = %v", err)
+	}
+	if want := "{\"level\":\"info\",\"ms\":0,\"msg\":\"start\"}\n{\"level\":\"warn\",\"ms\":1200,\"msg\":\"slow\"}\n"; string(got) != want {
+		t.Errorf("Exchange() got = %s, want %s", got, want)
+	}
+
+	_, err = NewTransfer(FormatNDJSON, FormatJSON).Exchange([]byte("{}\n{\"a\" 1}\n"))
+	var se *jsone.SyntaxError
+	if !errors.As(err, &se) || se.Line != 2 {
+		t.Errorf("Exchange() error = %v, want a syntax error on line 2", err)
+	}
+	if d := Validate(FormatNDJSON, []byte("{}\n{\"a\" 1}\n")); len(d) != 1 || d[0].Line != 2 || d[0].Column != 6 {
+		t.Errorf("Validate() = %v, want an error at 2:6", d)
+	}
+}
+
 func UnifiedTreatment(data []byte) []byte {
 	data = bytes.TrimLeft(data, "\n")
 	data = bytes.TrimRight(data, "\n")
@@ -334,6 +1022,66 @@ func TestTransfer_ExchangeED(t1 *testing.T) {
 			wantW:   jsonStr,
 			wantErr: false,
 		},
+		{
+			name: "jsonc-to-json",
+			fields: fields{
+				in:  FormatJSONC,
+				out: FormatJSON,
+			},
+			args: args{
+				r: strings.NewReader(jsoncStr),
+			},
+			wantW:   jsonStr,
+			wantErr: false,
+		},
+		{
+			name: "multi-yaml-to-json",
+			fields: fields{
+				in:  FormatYaml,
+				out: FormatJSON,
+			},
+			args: args{
+				r: strings.NewReader("kind: Service\n---\nkind: Deployment\n"),
+			},
+			wantW:   "{\"kind\":\"Service\"}\n{\"kind\":\"Deployment\"}",
+			wantErr: false,
+		},
+		{
+			name: "jsonc-stream-to-yaml",
+			fields: fields{
+				in:  FormatJSONC,
+				out: FormatYaml,
+			},
+			args: args{
+				r: strings.NewReader("{\"a\":1,} // first\n{\"b\":2}\n"),
+			},
+			wantW:   "a: 1\n---\nb: 2",
+			wantErr: false,
+		},
+		{
+			name: "json-stream-to-yaml",
+			fields: fields{
+				in:  FormatJSON,
+				out: FormatYaml,
+			},
+			args: args{
+				r: strings.NewReader("{\"a\":1}\n{\"b\":2}\n"),
+			},
+			wantW:   "a: 1\n---\nb: 2",
+			wantErr: false,
+		},
+		{
+			name: "multi-yaml-to-toml",
+			fields: fields{
+				in:  FormatYaml,
+				out: FormatTOML,
+			},
+			args: args{
+				r: strings.NewReader("a: 1\n---\nb: 2\n"),
+			},
+			wantW:   "[[documents]]\na = 1\n[[documents]]\nb = 2\n",
+			wantErr: false,
+		},
 	}
 	for _, tt := range tests {
 		t1.Run(tt.name, func(t1 *testing.T) {
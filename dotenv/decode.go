@@ -0,0 +1,222 @@
+// Package dotenv
+// Copyright © 2021 zc2638 <zc2638@qq.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package dotenv
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/99nil/ditto/flat"
+	"github.com/99nil/ditto/ordered"
+)
+
+// Unmarshal decodes the variables of data into v,
+// a *Document or an *interface{} decoded with the default options.
+//
+// Lines are KEY=value, optionally prefixed by export, and lines starting with '#' are comments,
+// as is the rest of an unquoted value from a '#' following a space.
+// Values may be quoted with single quotes, kept as they are, or with double quotes, where the escapes
+// \n, \r, \t, \", \\ and \$ are replaced. Quoted values may span several lines.
+// Variables are not expanded, and a repeated variable replaces the previous one.
+func Unmarshal(data []byte, v interface{}) error {
+	return NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+// Decoder reads an env file from an input stream, see Unmarshal.
+type Decoder struct {
+	r    io.Reader
+	done bool
+}
+
+// NewDecoder returns a new decoder that reads from r.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: r}
+}
+
+// Decode reads the variables of the input and stores them in v.
+// The input holds a single file, Decode returns io.EOF once it is read.
+func (dec *Decoder) Decode(v interface{}) error {
+	if dec.done {
+		return io.EOF
+	}
+	var doc *Document
+	switch val := v.(type) {
+	case *Document:
+		doc = val
+	case *interface{}:
+		doc = &Document{}
+	default:
+		return fmt.Errorf("cannot decode an env file into %T", v)
+	}
+	data, err := ioutil.ReadAll(dec.r)
+	if err != nil {
+		return err
+	}
+	if err := doc.read(data); err != nil {
+		return err
+	}
+	dec.done = true
+	if p, ok := v.(*interface{}); ok {
+		*p = doc.Value
+	}
+	return nil
+}
+
+// read decodes the variables of data.
+func (doc *Document) read(data []byte) error {
+	vars, err := parse(string(bytes.TrimPrefix(data, []byte("\ufeff"))))
+	if err != nil {
+		return err
+	}
+	if doc.Unflatten && len(vars) > 0 {
+		for i := range vars {
+			vars[i].Key = strings.ToLower(vars[i].Key)
+		}
+		value, err := flat.Unflatten(vars, doc.flat(doc.Ordered))
+		if err != nil {
+			return err
+		}
+		doc.Value = value
+		return nil
+	}
+	if doc.Ordered {
+		doc.Value = vars
+		return nil
+	}
+	m := make(map[string]interface{}, len(vars))
+	for _, item := range vars {
+		m[item.Key] = item.Value
+	}
+	doc.Value = m
+	return nil
+}
+
+// parse returns the variables of data in order.
+func parse(data string) (ordered.Map, error) {
+	vars := ordered.Map{}
+	p := &parser{data: strings.NewReplacer("\r\n", "\n", "\r", "\n").Replace(data), line: 1}
+	for p.pos < len(p.data) {
+		line := p.next()
+		text := strings.TrimSpace(line)
+		if text == "" || text[0] == '#' {
+			p.advance(len(line) + 1)
+			continue
+		}
+		start := p.line
+		text = strings.TrimLeft(line, " \t")
+		if rest := strings.TrimPrefix(text, "export"); rest != text && (rest == "" || rest[0] == ' ' || rest[0] == '\t') {
+			text = strings.TrimLeft(rest, " \t")
+		}
+		eq := strings.IndexByte(text, '=')
+		if eq < 0 {
+			return nil, &SyntaxError{Line: start, Msg: fmt.Sprintf("expected '=' after variable name in %q", strings.TrimSpace(text))}
+		}
+		key := strings.TrimSpace(text[:eq])
+		if key == "" || strings.ContainsAny(key, " \t") {
+			return nil, &SyntaxError{Line: start, Msg: fmt.Sprintf("invalid variable name %q", key)}
+		}
+		// the value may span several lines when quoted
+		p.advance(len(line) - len(text) + eq + 1)
+		value, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		vars.Set(key, value)
+	}
+	return vars, nil
+}
+
+// parser reads the values of an env file.
+type parser struct {
+	data string
+	pos  int
+	line int
+}
+
+// next returns the rest of the current line.
+func (p *parser) next() string {
+	line := p.data[p.pos:]
+	if i := strings.IndexByte(line, '\n'); i >= 0 {
+		line = line[:i]
+	}
+	return line
+}
+
+// advance moves n bytes forward, counting lines.
+func (p *parser) advance(n int) {
+	if p.pos+n > len(p.data) {
+		n = len(p.data) - p.pos
+	}
+	p.line += strings.Count(p.data[p.pos:p.pos+n], "\n")
+	p.pos += n
+}
+
+// value reads the value starting at the current position and the rest of its last line.
+func (p *parser) value() (string, error) {
+	line := p.next()
+	text := strings.TrimLeft(line, " \t")
+	if text == "" || (text[0] != '"' && text[0] != '\'') {
+		p.advance(len(line) + 1)
+		for i := 1; i < len(text); i++ {
+			if text[i] == '#' && (text[i-1] == ' ' || text[i-1] == '\t') {
+				text = text[:i]
+				break
+			}
+		}
+		return strings.TrimSpace(text), nil
+	}
+
+	start := p.line
+	quote := text[0]
+	p.advance(len(line) - len(text) + 1)
+	var b strings.Builder
+	for {
+		if p.pos >= len(p.data) {
+			return "", &SyntaxError{Line: start, Msg: fmt.Sprintf("expected %c after quoted value", quote)}
+		}
+		c := p.data[p.pos]
+		p.advance(1)
+		if c == quote {
+			break
+		}
+		if c != '\\' || quote != '"' || p.pos >= len(p.data) {
+			b.WriteByte(c)
+			continue
+		}
+		switch e := p.data[p.pos]; e {
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		case '"', '\\', '$':
+			b.WriteByte(e)
+		default:
+			b.WriteByte(c)
+			b.WriteByte(e)
+		}
+		p.advance(1)
+	}
+	end, rest := p.line, p.next()
+	p.advance(len(rest) + 1)
+	if rest = strings.TrimSpace(rest); rest != "" && rest[0] != '#' {
+		return "", &SyntaxError{Line: end, Msg: fmt.Sprintf("unexpected %q after quoted value", rest)}
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,68 @@
+// Package dotenv
+// Copyright © 2021 zc2638 <zc2638@qq.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package dotenv
+
+import (
+	"fmt"
+
+	"github.com/99nil/ditto/flat"
+)
+
+// DefaultSeparator joins the keys of nested values when Options.Separator is empty.
+const DefaultSeparator = "_"
+
+// Options configures how env files are decoded and encoded.
+type Options struct {
+	// Separator joins the keys of nested maps into variable names, DefaultSeparator when empty.
+	// A separator not found in plain keys, such as "__", keeps the names unambiguous.
+	Separator string
+	// Unflatten nests the variables at Separator when decoding, their names lowercased,
+	// such as DATABASE_HOST into database.host, instead of keeping them as they are.
+	Unflatten bool
+	// Export writes the variables with the export prefix of shell scripts.
+	Export bool
+}
+
+func (o *Options) separator() string {
+	if o.Separator == "" {
+		return DefaultSeparator
+	}
+	return o.Separator
+}
+
+func (o *Options) flat(ordered bool) flat.Options {
+	return flat.Options{Separator: o.separator(), Ordered: ordered}
+}
+
+// Document is an env file with the options to decode and encode it.
+// Its value is a map of the variables, nested when unflattened.
+type Document struct {
+	Options
+	// Ordered decodes the variables as ordered.Maps following the file.
+	Ordered bool
+	Value   interface{}
+}
+
+// SyntaxError reports a line that is neither a variable nor a comment.
+type SyntaxError struct {
+	// Line is the 1-based line of the error.
+	Line int
+	// Msg explains what was expected.
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Msg)
+}
@@ -0,0 +1,187 @@
+// Package dotenv
+// Copyright © 2021 zc2638 <zc2638@qq.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package dotenv
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/99nil/ditto/ordered"
+)
+
+func TestUnmarshal(t *testing.T) {
+	const data = `# database
+export DATABASE_HOST=127.0.0.1
+DATABASE_PORT = 5432   # inline comment
+DATABASE_PASSWORD='p#ss $word'
+GREETING="Hello,\n\"world\" \$HOME"
+CERT="-----BEGIN-----
+abc
+-----END-----"
+EMPTY=
+URL=http://host/#anchor
+`
+	tests := []struct {
+		name    string
+		opts    Options
+		ordered bool
+		want    interface{}
+	}{
+		{
+			name: "flat",
+			want: map[string]interface{}{
+				"DATABASE_HOST":     "127.0.0.1",
+				"DATABASE_PORT":     "5432",
+				"DATABASE_PASSWORD": "p#ss $word",
+				"GREETING":          "Hello,\n\"world\" $HOME",
+				"CERT":              "-----BEGIN-----\nabc\n-----END-----",
+				"EMPTY":             "",
+				"URL":               "http://host/#anchor",
+			},
+		},
+		{
+			name:    "unflatten",
+			opts:    Options{Unflatten: true},
+			ordered: true,
+			want: ordered.Map{
+				{Key: "database", Value: ordered.Map{
+					{Key: "host", Value: "127.0.0.1"},
+					{Key: "port", Value: "5432"},
+					{Key: "password", Value: "p#ss $word"},
+				}},
+				{Key: "greeting", Value: "Hello,\n\"world\" $HOME"},
+				{Key: "cert", Value: "-----BEGIN-----\nabc\n-----END-----"},
+				{Key: "empty", Value: ""},
+				{Key: "url", Value: "http://host/#anchor"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := &Document{Options: tt.opts, Ordered: tt.ordered}
+			if err := Unmarshal([]byte(data), doc); err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+			if !reflect.DeepEqual(doc.Value, tt.want) {
+				t.Errorf("Unmarshal() = %#v, want %#v", doc.Value, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnmarshal_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		wantLine int
+	}{
+		{name: "missing equal sign", data: "A=1\nB\n", wantLine: 2},
+		{name: "invalid name", data: "MY VAR=1\n", wantLine: 1},
+		{name: "unclosed quote", data: "A=1\nB=\"x\ny\n", wantLine: 2},
+		{name: "text after quote", data: "A='x' y\n", wantLine: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var v interface{}
+			err := Unmarshal([]byte(tt.data), &v)
+			var se *SyntaxError
+			if !errors.As(err, &se) {
+				t.Fatalf("Unmarshal() error = %v, want *SyntaxError", err)
+			}
+			if se.Line != tt.wantLine {
+				t.Errorf("Line = %d, want %d", se.Line, tt.wantLine)
+			}
+		})
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	v := ordered.Map{
+		{Key: "database", Value: ordered.Map{
+			{Key: "connectionMax", Value: float64(5000)},
+			{Key: "ports", Value: []interface{}{float64(8001), float64(8002)}},
+			{Key: "server-name", Value: "db.local"},
+		}},
+		{Key: "greeting", Value: "Hello world"},
+		{Key: "quote", Value: "it's\n$HOME"},
+		{Key: "debug", Value: true},
+		{Key: "none", Value: nil},
+	}
+	tests := []struct {
+		name string
+		opts Options
+		want string
+	}{
+		{
+			name: "default",
+			want: `DATABASE_CONNECTION_MAX=5000
+DATABASE_PORTS_0=8001
+DATABASE_PORTS_1=8002
+DATABASE_SERVER_NAME=db.local
+GREETING='Hello world'
+QUOTE="it's\n\$HOME"
+DEBUG=true
+NONE=
+`,
+		},
+		{
+			name: "separator and export",
+			opts: Options{Separator: "__", Export: true},
+			want: `export DATABASE__CONNECTION_MAX=5000
+export DATABASE__PORTS__0=8001
+export DATABASE__PORTS__1=8002
+export DATABASE__SERVER_NAME=db.local
+export GREETING='Hello world'
+export QUOTE="it's\n\$HOME"
+export DEBUG=true
+export NONE=
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Marshal(Document{Options: tt.opts, Value: v})
+			if err != nil {
+				t.Fatalf("Marshal() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Marshal() = %s, want %s", got, tt.want)
+			}
+
+			doc := &Document{Options: Options{Separator: tt.opts.Separator, Unflatten: true}}
+			if err := Unmarshal(got, doc); err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+			m := doc.Value.(map[string]interface{})
+			if m["quote"] != "it's\n$HOME" {
+				t.Errorf("Unmarshal() quote = %q, want %q", m["quote"], "it's\n$HOME")
+			}
+		})
+	}
+
+	got, err := Marshal(map[string]interface{}{})
+	if err != nil || len(got) != 0 {
+		t.Errorf("Marshal() = %q, %v, want nothing for an empty map", got, err)
+	}
+	doc := &Document{}
+	if err := Unmarshal(got, doc); err != nil || !reflect.DeepEqual(doc.Value, map[string]interface{}{}) {
+		t.Errorf("Unmarshal() = %#v, %v, want an empty map", doc.Value, err)
+	}
+
+	if _, err := Marshal([]interface{}{"a"}); err == nil {
+		t.Error("Marshal() expected error for an array")
+	}
+}
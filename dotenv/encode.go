@@ -0,0 +1,139 @@
+// Package dotenv
+// Copyright © 2021 zc2638 <zc2638@qq.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package dotenv
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/99nil/ditto/flat"
+	"github.com/99nil/ditto/ordered"
+)
+
+// Marshal returns the env file of v, a Document or a map encoded with the default options.
+// Nested values are flattened into UPPER_SNAKE names joined by the separator,
+// such as database.connectionMax into DATABASE_CONNECTION_MAX, array members by index.
+// Values other than plain words are quoted, with single quotes when possible.
+func Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Encoder writes env files to an output stream, see Marshal.
+type Encoder struct {
+	w io.Writer
+}
+
+// NewEncoder returns a new encoder that writes to w.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: w}
+}
+
+// Encode writes the env file of v to the stream.
+func (enc *Encoder) Encode(v interface{}) error {
+	doc := document(v)
+	switch doc.Value.(type) {
+	case map[string]interface{}, ordered.Map:
+	default:
+		return fmt.Errorf("cannot encode %T as an env file", doc.Value)
+	}
+	var buf bytes.Buffer
+	// the keys are joined by a byte they cannot hold, to be named one by one
+	for _, item := range flat.Flatten(doc.Value, flat.Options{Separator: "\x00"}) {
+		if doc.Export {
+			buf.WriteString("export ")
+		}
+		buf.WriteString(doc.name(strings.Split(item.Key, "\x00")))
+		buf.WriteByte('=')
+		buf.WriteString(quote(flat.Text(item.Value)))
+		buf.WriteByte('\n')
+	}
+	_, err := enc.w.Write(buf.Bytes())
+	return err
+}
+
+// document returns v as a Document.
+func document(v interface{}) *Document {
+	switch doc := v.(type) {
+	case Document:
+		return &doc
+	case *Document:
+		if doc != nil {
+			return doc
+		}
+	}
+	return &Document{Value: v}
+}
+
+// name returns the variable name of the keys of a nested value.
+func (o *Options) name(keys []string) string {
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		parts = append(parts, snake(key))
+	}
+	return strings.Join(parts, o.separator())
+}
+
+// snake writes key in UPPER_SNAKE case, such as connectionMax as CONNECTION_MAX,
+// the characters other than ASCII letters and digits becoming underscores.
+func snake(key string) string {
+	var b strings.Builder
+	for i := 0; i < len(key); i++ {
+		switch c := key[i]; {
+		case c >= 'A' && c <= 'Z':
+			if i > 0 && (isLower(key[i-1]) || isDigit(key[i-1])) {
+				b.WriteByte('_')
+			}
+			b.WriteByte(c)
+		case isLower(c):
+			b.WriteByte(c - 'a' + 'A')
+		case isDigit(c):
+			b.WriteByte(c)
+		default:
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
+
+func isLower(c byte) bool {
+	return c >= 'a' && c <= 'z'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// quote quotes s unless it is made of characters read as they are.
+func quote(s string) string {
+	plain := true
+	for i := 0; i < len(s) && plain; i++ {
+		c := s[i]
+		plain = isLower(c) || isDigit(c) || (c >= 'A' && c <= 'Z') || strings.IndexByte("_-./:@,+%=", c) >= 0
+	}
+	if plain {
+		return s
+	}
+	if !strings.ContainsAny(s, "'\n\r") {
+		return "'" + s + "'"
+	}
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `\$`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+	return `"` + r.Replace(s) + `"`
+}
@@ -0,0 +1,56 @@
+// Package ditto
+// Copyright © 2021 zc2638 <zc2638@qq.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package ditto
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrEngineNotFound is returned when no engine is registered under a format name.
+	ErrEngineNotFound = errors.New("engine not found")
+	// ErrCodecUnsupported is returned when an engine was registered without
+	// marshal and unmarshal functions, e.g. only through RegisterED.
+	ErrCodecUnsupported = errors.New("marshal and unmarshal unsupported")
+	// ErrStreamingUnsupported is returned when an engine was registered without
+	// encoder and decoder functions, e.g. only through Register.
+	ErrStreamingUnsupported = errors.New("streaming unsupported")
+	// ErrUnknownFormat is returned when the format of some data cannot be detected.
+	ErrUnknownFormat = errors.New("unknown format")
+)
+
+// Direction tells whether an engine is used to read or to write data.
+type Direction string
+
+const (
+	DirectionInput  Direction = "input"
+	DirectionOutput Direction = "output"
+)
+
+// EngineError records a failure to use the named engine in the given direction.
+type EngineError struct {
+	Name      string
+	Direction Direction
+	Err       error
+}
+
+func (e *EngineError) Error() string {
+	return fmt.Sprintf("%s engine %q: %v", e.Direction, e.Name, e.Err)
+}
+
+func (e *EngineError) Unwrap() error {
+	return e.Err
+}
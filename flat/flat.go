@@ -0,0 +1,298 @@
+// Package flat
+// Copyright © 2021 zc2638 <zc2638@qq.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package flat
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/99nil/ditto/ordered"
+)
+
+// DefaultSeparator joins the keys of nested values when Options.Separator is empty.
+const DefaultSeparator = "."
+
+// ErrConflict reports a key holding both a value and nested keys, such as "a" and "a.b".
+var ErrConflict = errors.New("conflicting key")
+
+// ErrIndex reports an array index in brackets too large for the elements given,
+// such as "a[2000000000]".
+var ErrIndex = errors.New("array index out of range")
+
+// maxHoles bounds the nil elements filling the gaps between the indices of an array.
+const maxHoles = 1024
+
+// Options configures how the paths of nested values are written as keys.
+type Options struct {
+	// Separator joins the keys of nested maps, DefaultSeparator when empty.
+	Separator string
+	// Brackets writes array indices in brackets after the key, such as "list[0]",
+	// instead of as keys joined by Separator, such as "list.0".
+	Brackets bool
+	// Ordered makes Unflatten return ordered.Maps instead of maps.
+	Ordered bool
+}
+
+func (o *Options) separator() string {
+	if o.Separator == "" {
+		return DefaultSeparator
+	}
+	return o.Separator
+}
+
+// Flatten returns the leaf values of v keyed by their path, in order.
+// The keys of maps are walked sorted, those of ordered.Maps in their order.
+// Empty maps and arrays are leaves, but an empty v has none, and a scalar v is keyed by the empty string.
+func Flatten(v interface{}, opts Options) ordered.Map {
+	var items ordered.Map
+	if !empty(v) {
+		flatten(&items, "", v, &opts)
+	}
+	return items
+}
+
+// empty reports whether v is an empty map or array.
+func empty(v interface{}) bool {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		return len(val) == 0
+	case ordered.Map:
+		return len(val) == 0
+	case []interface{}:
+		return len(val) == 0
+	}
+	return false
+}
+
+func flatten(items *ordered.Map, path string, v interface{}, opts *Options) {
+	if empty(v) {
+		*items = append(*items, ordered.Item{Key: path, Value: v})
+		return
+	}
+	switch val := v.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(val))
+		for key := range val {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			flatten(items, join(path, key, opts), val[key], opts)
+		}
+		return
+	case ordered.Map:
+		for _, item := range val {
+			flatten(items, join(path, item.Key, opts), item.Value, opts)
+		}
+		return
+	case []interface{}:
+		for i, elem := range val {
+			index := strconv.Itoa(i)
+			if opts.Brackets {
+				flatten(items, path+"["+index+"]", elem, opts)
+			} else {
+				flatten(items, join(path, index, opts), elem, opts)
+			}
+		}
+		return
+	}
+	*items = append(*items, ordered.Item{Key: path, Value: v})
+}
+
+// Text formats a leaf value as text. Floats are written without exponent,
+// nil and empty maps and arrays are empty, other values are formatted by fmt.Sprint.
+func Text(v interface{}) string {
+	switch val := v.(type) {
+	case nil, map[string]interface{}, ordered.Map, []interface{}:
+		return ""
+	case string:
+		return val
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(val), 'f', -1, 32)
+	}
+	return fmt.Sprint(v)
+}
+
+func join(path, key string, opts *Options) string {
+	if path == "" {
+		return key
+	}
+	return path + opts.separator() + key
+}
+
+// node is a value being unflattened, a leaf or the parent of nested keys.
+type node struct {
+	value    interface{}
+	leaf     bool
+	keys     []string
+	children map[string]*node
+	// whether the nested keys are array indices written in brackets
+	indexed bool
+}
+
+func (n *node) child(key string) *node {
+	if n.children == nil {
+		n.children = make(map[string]*node)
+	}
+	c, ok := n.children[key]
+	if !ok {
+		c = &node{}
+		n.children[key] = c
+		n.keys = append(n.keys, key)
+	}
+	return c
+}
+
+// Unflatten builds the nested value of the items keyed by path, the reverse of Flatten.
+// Nested keys are arrays when they are the indices 0 to n-1 in any order,
+// or indices written in brackets when Options.Brackets is set, missing members being nil.
+// A key repeated with a value replaces the previous one.
+func Unflatten(items ordered.Map, opts Options) (interface{}, error) {
+	root := &node{}
+	for _, item := range items {
+		n := root
+		for _, seg := range split(item.Key, &opts) {
+			if n.leaf {
+				return nil, fmt.Errorf("%w: %s", ErrConflict, item.Key)
+			}
+			if seg.index {
+				n.indexed = true
+			}
+			n = n.child(seg.key)
+		}
+		if n.children != nil {
+			return nil, fmt.Errorf("%w: %s", ErrConflict, item.Key)
+		}
+		n.value, n.leaf = item.Value, true
+	}
+	if root.children == nil {
+		return root.value, nil
+	}
+	return build(root, &opts)
+}
+
+type segment struct {
+	key   string
+	index bool
+}
+
+// split returns the segments of a key path.
+func split(key string, opts *Options) []segment {
+	var segs []segment
+	for _, part := range strings.Split(key, opts.separator()) {
+		if !opts.Brackets {
+			segs = append(segs, segment{key: part})
+			continue
+		}
+		name := part
+		var indices []segment
+		for strings.HasSuffix(name, "]") {
+			i := strings.LastIndex(name, "[")
+			if i < 0 || !isIndex(name[i+1:len(name)-1]) {
+				break
+			}
+			indices = append([]segment{{key: name[i+1 : len(name)-1], index: true}}, indices...)
+			name = name[:i]
+		}
+		if name != "" || len(indices) == 0 {
+			segs = append(segs, segment{key: name})
+		}
+		segs = append(segs, indices...)
+	}
+	return segs
+}
+
+func isIndex(s string) bool {
+	if s == "" || (len(s) > 1 && s[0] == '0') {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func build(n *node, opts *Options) (interface{}, error) {
+	if n.leaf {
+		return n.value, nil
+	}
+	if arr, ok, err := buildArray(n, opts); ok || err != nil {
+		return arr, err
+	}
+	if opts.Ordered {
+		m := make(ordered.Map, 0, len(n.keys))
+		for _, key := range n.keys {
+			v, err := build(n.children[key], opts)
+			if err != nil {
+				return nil, err
+			}
+			m = append(m, ordered.Item{Key: key, Value: v})
+		}
+		return m, nil
+	}
+	m := make(map[string]interface{}, len(n.keys))
+	for _, key := range n.keys {
+		v, err := build(n.children[key], opts)
+		if err != nil {
+			return nil, err
+		}
+		m[key] = v
+	}
+	return m, nil
+}
+
+// buildArray builds the array of a node whose nested keys are indices.
+func buildArray(n *node, opts *Options) ([]interface{}, bool, error) {
+	if opts.Brackets && !n.indexed {
+		return nil, false, nil
+	}
+	indices := make([]int, 0, len(n.keys))
+	size := 0
+	for _, key := range n.keys {
+		if !isIndex(key) {
+			return nil, false, nil
+		}
+		i, err := strconv.Atoi(key)
+		if err != nil || i >= len(n.keys)+maxHoles {
+			if opts.Brackets {
+				return nil, false, fmt.Errorf("%w: %s", ErrIndex, key)
+			}
+			return nil, false, nil
+		}
+		if i >= size {
+			size = i + 1
+		}
+		indices = append(indices, i)
+	}
+	if !opts.Brackets && size != len(n.keys) {
+		return nil, false, nil
+	}
+	arr := make([]interface{}, size)
+	for j, key := range n.keys {
+		v, err := build(n.children[key], opts)
+		if err != nil {
+			return nil, false, err
+		}
+		arr[indices[j]] = v
+	}
+	return arr, true, nil
+}
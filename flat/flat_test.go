@@ -0,0 +1,187 @@
+// Package flat
+// Copyright © 2021 zc2638 <zc2638@qq.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package flat
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/99nil/ditto/ordered"
+)
+
+func TestFlatten(t *testing.T) {
+	v := ordered.Map{
+		{Key: "name", Value: "ditto"},
+		{Key: "owner", Value: map[string]interface{}{"name": "zc", "id": 1}},
+		{Key: "tags", Value: []interface{}{"a", map[string]interface{}{"b": true}}},
+		{Key: "empty", Value: []interface{}{}},
+	}
+	tests := []struct {
+		name string
+		opts Options
+		want ordered.Map
+	}{
+		{
+			name: "dotted",
+			want: ordered.Map{
+				{Key: "name", Value: "ditto"},
+				{Key: "owner.id", Value: 1},
+				{Key: "owner.name", Value: "zc"},
+				{Key: "tags.0", Value: "a"},
+				{Key: "tags.1.b", Value: true},
+				{Key: "empty", Value: []interface{}{}},
+			},
+		},
+		{
+			name: "brackets",
+			opts: Options{Separator: "_", Brackets: true},
+			want: ordered.Map{
+				{Key: "name", Value: "ditto"},
+				{Key: "owner_id", Value: 1},
+				{Key: "owner_name", Value: "zc"},
+				{Key: "tags[0]", Value: "a"},
+				{Key: "tags[1]_b", Value: true},
+				{Key: "empty", Value: []interface{}{}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Flatten(v, tt.opts); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Flatten() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+	for _, v := range []interface{}{map[string]interface{}{}, ordered.Map{}, []interface{}{}} {
+		if got := Flatten(v, Options{}); len(got) != 0 {
+			t.Errorf("Flatten(%#v) = %#v, want no items", v, got)
+		}
+	}
+}
+
+func TestText(t *testing.T) {
+	tests := []struct {
+		v    interface{}
+		want string
+	}{
+		{v: nil, want: ""},
+		{v: "a b", want: "a b"},
+		{v: float64(1e21), want: "1000000000000000000000"},
+		{v: float32(0.1), want: "0.1"},
+		{v: int64(-3), want: "-3"},
+		{v: true, want: "true"},
+		{v: map[string]interface{}{}, want: ""},
+		{v: []interface{}{}, want: ""},
+	}
+	for _, tt := range tests {
+		if got := Text(tt.v); got != tt.want {
+			t.Errorf("Text(%#v) = %q, want %q", tt.v, got, tt.want)
+		}
+	}
+}
+
+func TestUnflatten(t *testing.T) {
+	tests := []struct {
+		name    string
+		items   ordered.Map
+		opts    Options
+		want    interface{}
+		wantErr error
+	}{
+		{
+			name: "dotted",
+			items: ordered.Map{
+				{Key: "name", Value: "ditto"},
+				{Key: "owner.name", Value: "zc"},
+				{Key: "tags.1", Value: "b"},
+				{Key: "tags.0", Value: "a"},
+				{Key: "codes.200", Value: "ok"},
+			},
+			want: map[string]interface{}{
+				"name":  "ditto",
+				"owner": map[string]interface{}{"name": "zc"},
+				"tags":  []interface{}{"a", "b"},
+				"codes": map[string]interface{}{"200": "ok"},
+			},
+		},
+		{
+			name: "brackets",
+			items: ordered.Map{
+				{Key: "list[0].name", Value: "a"},
+				{Key: "list[2].name", Value: "c"},
+				{Key: "matrix[0][1]", Value: 1},
+				{Key: "codes.0", Value: "zero"},
+			},
+			opts: Options{Brackets: true},
+			want: map[string]interface{}{
+				"list":   []interface{}{map[string]interface{}{"name": "a"}, nil, map[string]interface{}{"name": "c"}},
+				"matrix": []interface{}{[]interface{}{nil, 1}},
+				"codes":  map[string]interface{}{"0": "zero"},
+			},
+		},
+		{
+			name: "ordered",
+			items: ordered.Map{
+				{Key: "b", Value: 1},
+				{Key: "a.y", Value: 2},
+				{Key: "a.x", Value: 3},
+			},
+			opts: Options{Ordered: true},
+			want: ordered.Map{
+				{Key: "b", Value: 1},
+				{Key: "a", Value: ordered.Map{{Key: "y", Value: 2}, {Key: "x", Value: 3}}},
+			},
+		},
+		{
+			name:    "value then nested key",
+			items:   ordered.Map{{Key: "a", Value: 1}, {Key: "a.b", Value: 2}},
+			wantErr: ErrConflict,
+		},
+		{
+			name:    "nested key then value",
+			items:   ordered.Map{{Key: "a.b", Value: 2}, {Key: "a", Value: 1}},
+			wantErr: ErrConflict,
+		},
+		{
+			name:    "overflowing index",
+			items:   ordered.Map{{Key: "a[99999999999999999999]", Value: 1}},
+			opts:    Options{Brackets: true},
+			wantErr: ErrIndex,
+		},
+		{
+			name:    "index far beyond the elements",
+			items:   ordered.Map{{Key: "a[2000000000]", Value: 1}},
+			opts:    Options{Brackets: true},
+			wantErr: ErrIndex,
+		},
+		{
+			name:  "large dotted index",
+			items: ordered.Map{{Key: "a.2000000000", Value: 1}},
+			want:  map[string]interface{}{"a": map[string]interface{}{"2000000000": 1}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Unflatten(tt.items, tt.opts)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Unflatten() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Unflatten() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,264 @@
+// Package ini
+// Copyright © 2021 zc2638 <zc2638@qq.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package ini
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/99nil/ditto/ordered"
+)
+
+// Unmarshal decodes the INI data into v, an *interface{} receiving maps
+// or an *ordered.Value receiving ordered.Maps in the order of the data.
+//
+// The keys before the first section are at the top level, and the keys of
+// a section such as [server.http] are nested under its dotted names.
+// A repeated key, or a key written with brackets such as key[] = value, makes an array.
+// Lines starting with ';' or '#' are comments, as is the rest of an unquoted value
+// from a ';' or '#' following a space. Values may be quoted with '"', escapes
+// being those of Go strings, or with single quotes, and a key without '=' or ':' is true.
+func Unmarshal(data []byte, v interface{}) error {
+	return NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+// Decoder reads an INI document from an input stream, see Unmarshal.
+type Decoder struct {
+	r    io.Reader
+	done bool
+}
+
+// NewDecoder returns a new decoder that reads from r.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: r}
+}
+
+// Decode reads the document of the input and stores it in v.
+// The input holds a single document, Decode returns io.EOF once it is read.
+func (dec *Decoder) Decode(v interface{}) error {
+	if dec.done {
+		return io.EOF
+	}
+	switch v.(type) {
+	case *interface{}, *ordered.Value:
+	default:
+		return fmt.Errorf("cannot decode an INI document into %T", v)
+	}
+	doc, err := parse(dec.r)
+	if err != nil {
+		return err
+	}
+	dec.done = true
+	switch val := v.(type) {
+	case *interface{}:
+		*val = plain(doc)
+	case *ordered.Value:
+		val.V = doc
+	}
+	return nil
+}
+
+// table is a section being decoded, whose values are strings, arrays of strings or tables.
+type table struct {
+	items ordered.Map
+}
+
+func (t *table) get(key string) (interface{}, bool) {
+	return t.items.Get(key)
+}
+
+// value returns the section as an ordered.Map.
+func (t *table) value() ordered.Map {
+	m := make(ordered.Map, 0, len(t.items))
+	for _, item := range t.items {
+		if sub, ok := item.Value.(*table); ok {
+			item.Value = sub.value()
+		}
+		m = append(m, item)
+	}
+	return m
+}
+
+// parser holds the state of the document being decoded.
+type parser struct {
+	root *table
+	// the current section, root for the global section
+	section *table
+	line    int
+}
+
+func parse(r io.Reader) (ordered.Map, error) {
+	root := &table{}
+	p := &parser{root: root, section: root}
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		p.line++
+		line := strings.TrimSpace(s.Text())
+		if p.line == 1 {
+			line = strings.TrimPrefix(line, "\ufeff")
+		}
+		if line == "" || line[0] == ';' || line[0] == '#' {
+			continue
+		}
+		var err error
+		if line[0] == '[' {
+			err = p.startSection(line)
+		} else {
+			err = p.key(line)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return root.value(), nil
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return &SyntaxError{Line: p.line, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *parser) conflict(what string) error {
+	return &SyntaxError{Line: p.line, Msg: what, Err: ErrConflict}
+}
+
+// startSection starts the section of a line such as [server.http], creating its parents.
+func (p *parser) startSection(line string) error {
+	end := strings.IndexByte(line, ']')
+	if end < 0 {
+		return p.errorf("expected ']' after section name")
+	}
+	if rest := strings.TrimSpace(line[end+1:]); rest != "" && rest[0] != ';' && rest[0] != '#' {
+		return p.errorf("unexpected %q after section", rest)
+	}
+	name := strings.TrimSpace(line[1:end])
+	if name == "" {
+		return p.errorf("empty section name")
+	}
+	t := p.root
+	for _, part := range strings.Split(name, ".") {
+		part = strings.TrimSpace(part)
+		v, ok := t.get(part)
+		if !ok {
+			v = &table{}
+			t.items = append(t.items, ordered.Item{Key: part, Value: v})
+		}
+		sub, ok := v.(*table)
+		if !ok {
+			return p.conflict("section " + name)
+		}
+		t = sub
+	}
+	p.section = t
+	return nil
+}
+
+// key adds the key of a line such as key = value to the current section.
+func (p *parser) key(line string) error {
+	var (
+		key   = line
+		value interface{}
+	)
+	if i := strings.IndexAny(line, "=:"); i >= 0 {
+		key = strings.TrimSpace(line[:i])
+		s, err := p.value(strings.TrimSpace(line[i+1:]))
+		if err != nil {
+			return err
+		}
+		value = s
+	} else {
+		value = true
+	}
+	array := strings.HasSuffix(key, "[]")
+	if array {
+		key = strings.TrimSpace(strings.TrimSuffix(key, "[]"))
+	}
+	if key == "" {
+		return p.errorf("expected key before %q", line)
+	}
+
+	items := &p.section.items
+	for i := range *items {
+		item := &(*items)[i]
+		if item.Key != key {
+			continue
+		}
+		switch prev := item.Value.(type) {
+		case *table:
+			return p.conflict("key " + key)
+		case []interface{}:
+			item.Value = append(prev, value)
+		default:
+			item.Value = []interface{}{prev, value}
+		}
+		return nil
+	}
+	if array {
+		value = []interface{}{value}
+	}
+	*items = append(*items, ordered.Item{Key: key, Value: value})
+	return nil
+}
+
+// value decodes the value following a key, without its comment.
+func (p *parser) value(s string) (string, error) {
+	if s == "" {
+		return "", nil
+	}
+	var (
+		value string
+		rest  string
+	)
+	switch s[0] {
+	case '"':
+		end := 1
+		for ; end < len(s) && s[end] != '"'; end++ {
+			if s[end] == '\\' {
+				end++
+			}
+		}
+		if end >= len(s) {
+			return "", p.errorf("expected '\"' after quoted value")
+		}
+		unquoted, err := strconv.Unquote(s[:end+1])
+		if err != nil {
+			return "", p.errorf("invalid quoted value %s", s[:end+1])
+		}
+		value, rest = unquoted, s[end+1:]
+	case '\'':
+		end := strings.IndexByte(s[1:], '\'')
+		if end < 0 {
+			return "", p.errorf("expected \"'\" after quoted value")
+		}
+		value, rest = s[1:end+1], s[end+2:]
+	default:
+		for i := 1; i < len(s); i++ {
+			if (s[i] == ';' || s[i] == '#') && (s[i-1] == ' ' || s[i-1] == '\t') {
+				return strings.TrimSpace(s[:i]), nil
+			}
+		}
+		return s, nil
+	}
+	if rest = strings.TrimSpace(rest); rest != "" && rest[0] != ';' && rest[0] != '#' {
+		return "", p.errorf("unexpected %q after quoted value", rest)
+	}
+	return value, nil
+}
@@ -0,0 +1,184 @@
+// Package ini
+// Copyright © 2021 zc2638 <zc2638@qq.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package ini
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/99nil/ditto/flat"
+	"github.com/99nil/ditto/ordered"
+)
+
+// Marshal returns the INI encoding of v, a map or an ordered.Map.
+// The scalars and arrays of scalars of the top level are written before the first section,
+// and nested maps as sections named by their dotted path, such as [server.http].
+// An array is written as a repeated key, or with brackets such as key[] = value
+// when it has a single member. Values are quoted when they would not be read back as is,
+// while keys and section names that would not be are an error.
+func Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Encoder writes INI documents to an output stream, see Marshal.
+type Encoder struct {
+	w io.Writer
+}
+
+// NewEncoder returns a new encoder that writes to w.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: w}
+}
+
+// Encode writes the INI encoding of v to the stream.
+func (enc *Encoder) Encode(v interface{}) error {
+	items, ok := itemsOf(v)
+	if !ok {
+		return fmt.Errorf("cannot encode %T as an INI document", v)
+	}
+	var buf bytes.Buffer
+	if err := writeSection(&buf, nil, items); err != nil {
+		return err
+	}
+	_, err := enc.w.Write(buf.Bytes())
+	return err
+}
+
+// itemsOf returns the items of a map in order, its keys sorted when it is not an ordered.Map.
+func itemsOf(v interface{}) (ordered.Map, bool) {
+	switch val := v.(type) {
+	case ordered.Map:
+		return val, true
+	case map[string]interface{}:
+		keys := make([]string, 0, len(val))
+		for key := range val {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		items := make(ordered.Map, 0, len(val))
+		for _, key := range keys {
+			items = append(items, ordered.Item{Key: key, Value: val[key]})
+		}
+		return items, true
+	}
+	return nil, false
+}
+
+// writeSection writes the keys of the section path, then its sections.
+func writeSection(buf *bytes.Buffer, path []string, items ordered.Map) error {
+	var sections ordered.Map
+	var keys bytes.Buffer
+	for _, item := range items {
+		name := strings.Join(append(path[:len(path):len(path)], item.Key), ".")
+		if sub, ok := itemsOf(item.Value); ok {
+			if !validSection(item.Key) {
+				return fmt.Errorf("section %q: cannot encode a name holding '.' or ']' or being empty or padded", name)
+			}
+			sections = append(sections, ordered.Item{Key: item.Key, Value: sub})
+			continue
+		}
+		if !validKey(item.Key) {
+			return fmt.Errorf("key %q: cannot encode a key holding '=' or ':', starting with '[', ';' or '#' or ending with \"[]\"", name)
+		}
+		arr, ok := item.Value.([]interface{})
+		if !ok {
+			writeKey(&keys, item.Key, item.Value)
+			continue
+		}
+		for _, elem := range arr {
+			switch elem.(type) {
+			case map[string]interface{}, ordered.Map, []interface{}:
+				return fmt.Errorf("key %s: cannot encode nested arrays or maps in arrays", name)
+			}
+		}
+		key := item.Key
+		if len(arr) == 1 {
+			key += "[]"
+		}
+		for _, elem := range arr {
+			writeKey(&keys, key, elem)
+		}
+	}
+	// a section holding only sections is implied by their names
+	if len(path) > 0 && (keys.Len() > 0 || len(items) == 0) {
+		if buf.Len() > 0 {
+			buf.WriteByte('\n')
+		}
+		fmt.Fprintf(buf, "[%s]\n", strings.Join(path, "."))
+	}
+	buf.Write(keys.Bytes())
+	for _, item := range sections {
+		if err := writeSection(buf, append(path[:len(path):len(path)], item.Key), item.Value.(ordered.Map)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeKey(buf *bytes.Buffer, key string, v interface{}) {
+	s := flat.Text(v)
+	if needsQuotes(s) {
+		s = strconv.Quote(s)
+	}
+	buf.WriteString(key)
+	buf.WriteString(" = ")
+	buf.WriteString(s)
+	buf.WriteByte('\n')
+}
+
+// validKey reports whether key is decoded as is in front of a value.
+func validKey(key string) bool {
+	if key == "" || key != strings.TrimSpace(key) || strings.ContainsAny(key, "=:") || strings.HasSuffix(key, "[]") {
+		return false
+	}
+	switch key[0] {
+	case '[', ';', '#':
+		return false
+	}
+	return !hasControls(key)
+}
+
+// validSection reports whether name is decoded as is as a part of a section name.
+func validSection(name string) bool {
+	return name != "" && name == strings.TrimSpace(name) && !strings.ContainsAny(name, ".]") && !hasControls(name)
+}
+
+func hasControls(s string) bool {
+	for _, c := range s {
+		if c < ' ' || c == 0x7f {
+			return true
+		}
+	}
+	return false
+}
+
+// needsQuotes reports whether s would not be decoded as is without quotes.
+func needsQuotes(s string) bool {
+	if s == "" {
+		return false
+	}
+	if s != strings.TrimSpace(s) || s[0] == '"' || s[0] == '\'' || strings.ContainsAny(s, ";#") {
+		return true
+	}
+	return hasControls(s)
+}
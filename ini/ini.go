@@ -0,0 +1,63 @@
+// Package ini
+// Copyright © 2021 zc2638 <zc2638@qq.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package ini
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/99nil/ditto/ordered"
+)
+
+// ErrConflict reports a key or section holding both a value and nested keys.
+var ErrConflict = errors.New("conflicting key")
+
+// SyntaxError reports a line that is neither a section, a key nor a comment.
+type SyntaxError struct {
+	// Line is the 1-based line of the error.
+	Line int
+	// Msg explains what was expected, or names the key of Err.
+	Msg string
+	// Err is the underlying error, such as ErrConflict, if any.
+	Err error
+}
+
+func (e *SyntaxError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("line %d: %s: %v", e.Line, e.Msg, e.Err)
+	}
+	return fmt.Sprintf("line %d: %s", e.Line, e.Msg)
+}
+
+func (e *SyntaxError) Unwrap() error {
+	return e.Err
+}
+
+// plain converts the ordered.Maps of v to maps.
+func plain(v interface{}) interface{} {
+	switch val := v.(type) {
+	case ordered.Map:
+		m := make(map[string]interface{}, len(val))
+		for _, item := range val {
+			m[item.Key] = plain(item.Value)
+		}
+		return m
+	case []interface{}:
+		for i, elem := range val {
+			val[i] = plain(elem)
+		}
+	}
+	return v
+}
@@ -0,0 +1,188 @@
+// Package ini
+// Copyright © 2021 zc2638 <zc2638@qq.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package ini
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/99nil/ditto/ordered"
+)
+
+func TestUnmarshal(t *testing.T) {
+	const data = `; global settings
+name = ditto
+debug
+
+[server]
+host = 127.0.0.1   ; inline comment
+port: 8080
+# repeated keys make arrays
+allow = 10.0.0.1
+allow = 10.0.0.2
+
+[server.tls]
+cert = "/etc/ssl/a b.pem"
+key = '#not a comment'
+
+[paths]
+include[] = /usr/lib
+`
+	want := map[string]interface{}{
+		"name":  "ditto",
+		"debug": true,
+		"server": map[string]interface{}{
+			"host":  "127.0.0.1",
+			"port":  "8080",
+			"allow": []interface{}{"10.0.0.1", "10.0.0.2"},
+			"tls": map[string]interface{}{
+				"cert": "/etc/ssl/a b.pem",
+				"key":  "#not a comment",
+			},
+		},
+		"paths": map[string]interface{}{
+			"include": []interface{}{"/usr/lib"},
+		},
+	}
+	var v interface{}
+	if err := Unmarshal([]byte(data), &v); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if !reflect.DeepEqual(v, want) {
+		t.Errorf("Unmarshal() = %#v, want %#v", v, want)
+	}
+
+	var ov ordered.Value
+	if err := Unmarshal([]byte(data), &ov); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if keys := ov.V.(ordered.Map).Keys(); !reflect.DeepEqual(keys, []string{"name", "debug", "server", "paths"}) {
+		t.Errorf("Unmarshal() keys = %v", keys)
+	}
+}
+
+func TestUnmarshal_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		wantLine int
+		wantErr  error
+	}{
+		{name: "unclosed section", data: "a = 1\n[server\n", wantLine: 2},
+		{name: "empty key", data: "= 1\n", wantLine: 1},
+		{name: "unclosed quote", data: "a = \"b\n", wantLine: 1},
+		{name: "section over key", data: "a = 1\n[a.b]\n", wantLine: 2, wantErr: ErrConflict},
+		{name: "key over section", data: "[a.b]\n[a]\nb = 1\n", wantLine: 3, wantErr: ErrConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var v interface{}
+			err := Unmarshal([]byte(tt.data), &v)
+			var se *SyntaxError
+			if !errors.As(err, &se) {
+				t.Fatalf("Unmarshal() error = %v, want *SyntaxError", err)
+			}
+			if se.Line != tt.wantLine {
+				t.Errorf("Line = %d, want %d", se.Line, tt.wantLine)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("Unmarshal() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	tests := []struct {
+		name    string
+		v       interface{}
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "sections",
+			v: ordered.Map{
+				{Key: "name", Value: "ditto"},
+				{Key: "server", Value: ordered.Map{
+					{Key: "port", Value: float64(8080)},
+					{Key: "tls", Value: map[string]interface{}{"cert": " a.pem", "on": true}},
+					{Key: "allow", Value: []interface{}{"10.0.0.1", "10.0.0.2"}},
+				}},
+				{Key: "paths", Value: map[string]interface{}{
+					"lib": map[string]interface{}{"include": []interface{}{"/usr/lib"}},
+				}},
+				{Key: "empty", Value: ordered.Map{}},
+			},
+			want: `name = ditto
+
+[server]
+port = 8080
+allow = 10.0.0.1
+allow = 10.0.0.2
+
+[server.tls]
+cert = " a.pem"
+on = true
+
+[paths.lib]
+include[] = /usr/lib
+
+[empty]
+`,
+		},
+		{
+			name:    "array of maps",
+			v:       map[string]interface{}{"a": []interface{}{map[string]interface{}{}}},
+			wantErr: true,
+		},
+		{
+			name:    "key with separator",
+			v:       map[string]interface{}{"a:b": "1"},
+			wantErr: true,
+		},
+		{
+			name:    "key starting a comment",
+			v:       map[string]interface{}{"s": map[string]interface{}{"#a": "1"}},
+			wantErr: true,
+		},
+		{
+			name:    "key with brackets",
+			v:       map[string]interface{}{"a[]": []interface{}{"1", "2"}},
+			wantErr: true,
+		},
+		{
+			name:    "dotted section",
+			v:       map[string]interface{}{"a.b": map[string]interface{}{"c": "1"}},
+			wantErr: true,
+		},
+		{
+			name:    "not a map",
+			v:       []interface{}{"a"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Marshal(tt.v)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Marshal() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Marshal() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
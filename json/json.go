@@ -14,357 +14,601 @@
 // limitations under the License.
 package json
 
+import (
+	stdjson "encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SyntaxError describes where and why a payload is not valid JSON.
+type SyntaxError struct {
+	// Msg explains what the checker expected, e.g. "expected ':' after object key".
+	Msg string
+	// Line is the 1-based line of the offending byte.
+	Line int
+	// Column is the 1-based column of the offending byte.
+	Column int
+	// Offset is the byte offset of the offending byte.
+	Offset int
+	// Char is the offending byte, meaningless when EOF is true.
+	Char byte
+	// EOF reports that the payload ended unexpectedly.
+	EOF bool
+	// Path is the nesting path leading to the failure, e.g. "$.b[1]".
+	Path string
+}
+
+func (e *SyntaxError) Error() string {
+	found := "end of input"
+	if !e.EOF {
+		found = quoteChar(e.Char)
+	}
+	return fmt.Sprintf("%d:%d: %s, found %s at %s", e.Line, e.Column, e.Msg, found, e.Path)
+}
+
+func quoteChar(c byte) string {
+	if c == '\'' {
+		return `'\''`
+	}
+	if c == '"' {
+		return `'"'`
+	}
+	s := strconv.Quote(string(c))
+	return "'" + s[1:len(s)-1] + "'"
+}
+
 func Check(payload string) (line, pos int, ok bool) {
 	return CheckBytes([]byte(payload))
 }
 
+// CheckBytes reports whether payload is valid JSON.
+// line is 1-based and pos is the 0-based byte offset within that line,
+// of the offending byte on failure or of the end of payload on success.
+// Before SyntaxError existed pos was counted from the preceding newline
+// on every line but the first, one more than it is now.
 func CheckBytes(payload []byte) (line, pos int, ok bool) {
 	return validPayload(payload, 1, 0)
 }
 
-type position struct {
-	line    int
-	linePos int
-	pos     int
-	ok      bool
+// Validate checks payload and returns a *SyntaxError describing the first problem,
+// or nil when payload is valid JSON.
+func Validate(payload []byte) error {
+	return ValidateMode(payload, Strict)
 }
 
-func (p *position) buildTrue(poss ...int) *position {
-	if len(poss) > 0 {
-		p.pos = poss[0]
+// ValidateMode is like Validate but accepts the relaxations enabled by mode.
+func ValidateMode(payload []byte, mode Mode) error {
+	c := newChecker(payload, 1, 0)
+	c.mode = mode
+	if c.payload() {
+		return nil
 	}
-	p.ok = true
-	return p
+	return c.errs[0]
 }
 
-func (p *position) buildFalse(poss ...int) *position {
-	if len(poss) > 0 {
-		p.pos = poss[0]
+// ValidateAll checks payload and returns up to max syntax errors, or all of them when max <= 0.
+// After an error the checker resynchronizes at the next comma, closing bracket
+// or line of the enclosing object or array, so a single run reports every problem.
+func ValidateAll(payload []byte, max int) []*SyntaxError {
+	return ValidateAllMode(payload, max, Strict)
+}
+
+// ValidateAllMode is like ValidateAll but accepts the relaxations enabled by mode.
+func ValidateAllMode(payload []byte, max int, mode Mode) []*SyntaxError {
+	c := newChecker(payload, 1, 0)
+	c.max = max
+	c.mode = mode
+	c.payload()
+	return c.errs
+}
+
+func validPayload(data []byte, l, i int) (line, outi int, ok bool) {
+	c := newChecker(data, l, i)
+	if !c.payload() {
+		return c.errs[0].Line, c.errs[0].Column - 1, false
 	}
-	p.ok = false
-	return p
+	return c.line, c.pos - c.lineStart, true
 }
 
-func (p *position) AddLine() {
-	p.line++
-	p.linePos = p.pos
+// segment is a step of the nesting path, an object key or an array index.
+type segment struct {
+	// key is the raw key including its quotes, if any
+	key   []byte
+	index int
 }
 
-func (p *position) out() (line, pos int, ok bool) {
-	return p.line, p.pos - p.linePos, p.ok
+type checker struct {
+	data      []byte
+	mode      Mode
+	pos       int
+	line      int
+	lineStart int
+	path      []segment
+	errs      []*SyntaxError
+	// max is the number of errors to collect, recovery is disabled when it is 1
+	max int
+	// abort is set once no more errors may be collected
+	abort bool
 }
 
-func validPayload(data []byte, l, i int) (line, outi int, ok bool) {
-	in := &position{line: l, pos: i}
-	for ; in.pos < len(data); in.pos++ {
-		switch data[in.pos] {
-		default:
-			res := validAny(data, in)
-			if !res.ok {
-				return res.out()
-			}
-			for ; res.pos < len(data); res.pos++ {
-				switch data[res.pos] {
-				default:
-					return res.out()
-				case ' ', '\t', '\r':
-					continue
-				case '\n':
-					res.AddLine()
-					continue
+func newChecker(data []byte, line, pos int) *checker {
+	return &checker{data: data, pos: pos, line: line, max: 1}
+}
+
+// fail records a syntax error at the current position and returns false.
+func (c *checker) fail(msg string) bool {
+	err := &SyntaxError{
+		Msg:    msg,
+		Line:   c.line,
+		Column: c.pos - c.lineStart + 1,
+		Offset: c.pos,
+		EOF:    c.pos >= len(c.data),
+		Path:   c.pathString(),
+	}
+	if !err.EOF {
+		err.Char = c.data[c.pos]
+	}
+	c.errs = append(c.errs, err)
+	if c.max > 0 && len(c.errs) >= c.max {
+		c.abort = true
+	}
+	return false
+}
+
+type syncState int
+
+const (
+	// syncFail stops checking the enclosing object or array
+	syncFail syncState = iota
+	// syncNext continues with the next member or element
+	syncNext
+	// syncClose ends the enclosing object or array
+	syncClose
+)
+
+// resync skips the rest of a broken member or element of the object or array
+// closed by closer, stopping after a comma or newline, or at a closing bracket.
+func (c *checker) resync(closer byte) syncState {
+	if c.abort {
+		return syncFail
+	}
+	for ; c.pos < len(c.data); c.pos++ {
+		switch c.data[c.pos] {
+		case ',':
+			c.pos++
+			return syncNext
+		case '\n':
+			c.line++
+			c.lineStart = c.pos + 1
+			c.pos++
+			return syncNext
+		case closer:
+			c.pos++
+			return syncClose
+		case '}', ']':
+			// leave a mismatched bracket to the enclosing object or array
+			return syncClose
+		case '"', '\'':
+			// skip strings so their commas and brackets are ignored
+			quote := c.data[c.pos]
+			for c.pos++; c.pos < len(c.data) && c.data[c.pos] != quote && c.data[c.pos] != '\n'; c.pos++ {
+				if c.data[c.pos] == '\\' {
+					c.pos++
 				}
 			}
-			return res.buildTrue().out()
-		case ' ', '\t', '\r':
-			continue
-		case '\n':
-			in.AddLine()
+			if c.pos < len(c.data) && c.data[c.pos] == '\n' {
+				c.pos--
+			}
+		}
+	}
+	return syncFail
+}
+
+// recover resynchronizes after a failure inside the object or array closed by closer,
+// and reports whether checking should go on with the next member or element.
+// done is true when the object or array has been closed.
+func (c *checker) recover(closer byte) (next, done bool) {
+	switch c.resync(closer) {
+	case syncNext:
+		c.skipSpace()
+		switch c.peek() {
+		case closer:
+			c.pos++
+			return false, true
+		case '}', ']':
+			return false, true
+		}
+		return true, false
+	case syncClose:
+		return false, true
+	}
+	return false, false
+}
+
+func (c *checker) pathString() string {
+	var b strings.Builder
+	b.WriteByte('$')
+	for _, seg := range c.path {
+		if seg.key == nil {
+			b.WriteString("[" + strconv.Itoa(seg.index) + "]")
 			continue
 		}
+		key := unquoteKey(seg.key)
+		if isIdentifier(key) {
+			b.WriteString("." + key)
+		} else {
+			b.WriteString("[" + strconv.Quote(key) + "]")
+		}
+	}
+	return b.String()
+}
+
+// unquoteKey decodes a raw object key, which is quoted unless it is an identifier.
+func unquoteKey(raw []byte) string {
+	switch raw[0] {
+	case '"':
+	case '\'':
+		raw = requote(nil, raw)
+	default:
+		return string(raw)
+	}
+	var key string
+	if err := stdjson.Unmarshal(raw, &key); err != nil {
+		return string(raw[1 : len(raw)-1])
 	}
-	return in.out()
+	return key
 }
 
-func validAny(data []byte, in *position) *position {
-	for ; in.pos < len(data); in.pos++ {
-		switch data[in.pos] {
+func isIdentifier(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, r := range s {
+		switch {
+		case r == '_' || r == '$':
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z':
+		case r >= '0' && r <= '9' && i > 0:
 		default:
-			return in
-		case ' ', '\t', '\r':
-			continue
-		case '\n':
-			in.AddLine()
-			continue
-		case '{':
-			in.pos++
-			return validObject(data, in)
-		case '[':
-			in.pos++
-			return validArray(data, in)
-		case '"':
-			in.pos, in.ok = validString(data, in.pos+1)
-			return in
-		case '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
-			in.pos, in.ok = validNumber(data, in.pos+1)
-			return in
-		case 't':
-			in.pos, in.ok = validTrue(data, in.pos+1)
-			return in
-		case 'f':
-			in.pos, in.ok = validFalse(data, in.pos+1)
-			return in
-		case 'n':
-			in.pos, in.ok = validNull(data, in.pos+1)
-			return in
+			return false
 		}
 	}
-	return in
+	return true
 }
 
-// 校验对象
-func validObject(data []byte, in *position) *position {
-	for ; in.pos < len(data); in.pos++ {
-		switch data[in.pos] {
+// peek returns the current byte, or 0 at the end of data.
+func (c *checker) peek() byte {
+	if c.pos < len(c.data) {
+		return c.data[c.pos]
+	}
+	return 0
+}
+
+func (c *checker) skipSpace() {
+	for ; c.pos < len(c.data); c.pos++ {
+		switch c.data[c.pos] {
 		default:
-			return in.buildFalse()
+			return
 		case ' ', '\t', '\r':
-			continue
 		case '\n':
-			in.AddLine()
-			continue
-		case '}':
-			return in.buildTrue(in.pos + 1)
-		case '"':
-		key:
-			if in.pos, in.ok = validString(data, in.pos+1); !in.ok {
-				return in
-			}
-			if in = validColon(data, in); !in.ok {
-				return in
+			c.line++
+			c.lineStart = c.pos + 1
+		case '/':
+			if c.mode&AllowComments == 0 || !c.comment() {
+				return
 			}
-			if in = validAny(data, in); !in.ok {
-				return in
-			}
-			if in = validComma(data, in, '}'); !in.ok {
-				return in
-			}
-			if data[in.pos] == '}' {
-				return in.buildTrue(in.pos + 1)
+		}
+	}
+}
+
+// comment skips the comment starting at the current position
+// and leaves the position on its last byte.
+func (c *checker) comment() bool {
+	if c.pos+1 >= len(c.data) {
+		return false
+	}
+	switch c.data[c.pos+1] {
+	case '/':
+		for c.pos += 2; c.pos < len(c.data) && c.data[c.pos] != '\n'; c.pos++ {
+		}
+		// let the caller count the newline
+		c.pos--
+		return true
+	case '*':
+		for c.pos += 2; c.pos+1 < len(c.data); c.pos++ {
+			switch {
+			case c.data[c.pos] == '*' && c.data[c.pos+1] == '/':
+				c.pos++
+				return true
+			case c.data[c.pos] == '\n':
+				c.line++
+				c.lineStart = c.pos + 1
 			}
-			in.pos++
-			for ; in.pos < len(data); in.pos++ {
-				switch data[in.pos] {
-				default:
-					return in.buildFalse()
-				case ' ', '\t', '\r':
-					continue
-				case '\n':
-					in.AddLine()
+		}
+		// an unterminated comment runs to the end of the payload
+		c.pos = len(c.data) - 1
+		return true
+	}
+	return false
+}
+
+func (c *checker) payload() bool {
+	for {
+		if !c.value() {
+			return false
+		}
+		c.skipSpace()
+		if c.pos == len(c.data) {
+			return len(c.errs) == 0
+		}
+		if c.mode&AllowValueStream == 0 {
+			return c.fail("expected end of input after top-level value")
+		}
+	}
+}
+
+func (c *checker) value() bool {
+	c.skipSpace()
+	switch c.peek() {
+	case '{':
+		return c.object()
+	case '[':
+		return c.array()
+	case '"':
+		return c.string()
+	case '\'':
+		if c.mode&AllowSingleQuotes != 0 {
+			return c.string()
+		}
+	case '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+		return c.number()
+	case 't':
+		return c.literal("true")
+	case 'f':
+		return c.literal("false")
+	case 'n':
+		return c.literal("null")
+	}
+	return c.fail("expected value")
+}
+
+// 校验对象
+func (c *checker) object() bool {
+	c.pos++
+	c.skipSpace()
+	if c.peek() == '}' {
+		c.pos++
+		return true
+	}
+	depth := len(c.path)
+	for {
+		if c.member() {
+			// 校验逗号
+			c.skipSpace()
+			switch c.peek() {
+			case ',':
+				c.pos++
+				if c.trailingComma('}') {
+					return true
+				}
+				continue
+			case '}':
+				c.pos++
+				return true
+			default:
+				c.fail("expected ',' or '}' after object value")
+				// a missing comma, go on with the next key
+				if ch := c.peek(); !c.abort && (ch == '"' || c.startsKey(ch)) {
 					continue
-				case '"':
-					goto key
 				}
 			}
-			return in.buildFalse()
+		}
+		c.path = c.path[:depth]
+		if next, done := c.recover('}'); !next {
+			return done
 		}
 	}
-	return in.buildFalse()
 }
 
-// 校验冒号
-func validColon(data []byte, in *position) *position {
-	for ; in.pos < len(data); in.pos++ {
-		switch data[in.pos] {
-		default:
-			return in.buildFalse()
-		case ' ', '\t', '\r':
-			continue
-		case '\n':
-			in.AddLine()
-			continue
-		case ':':
-			return in.buildTrue(in.pos + 1)
-		}
+// trailingComma consumes closer when it follows a comma and trailing commas are allowed.
+func (c *checker) trailingComma(closer byte) bool {
+	if c.mode&AllowTrailingCommas == 0 {
+		return false
+	}
+	c.skipSpace()
+	if c.peek() != closer {
+		return false
 	}
-	return in
+	c.pos++
+	return true
 }
 
-// 校验逗号
-func validComma(data []byte, in *position, end byte) *position {
-	for ; in.pos < len(data); in.pos++ {
-		switch data[in.pos] {
-		default:
-			return in.buildFalse()
-		case ' ', '\t', '\r':
-			continue
-		case '\n':
-			in.AddLine()
-			continue
-		case ',':
-			return in.buildTrue()
-		case end:
-			return in.buildTrue()
+func (c *checker) member() bool {
+	c.skipSpace()
+	start := c.pos
+	switch ch := c.peek(); {
+	case ch == '"', ch == '\'' && c.mode&AllowSingleQuotes != 0:
+		if !c.string() {
+			return false
+		}
+	case c.startsKey(ch):
+		for c.pos++; isIdentPart(c.peek()); c.pos++ {
 		}
+	default:
+		return c.fail("expected string for object key")
+	}
+	c.path = append(c.path, segment{key: c.data[start:c.pos]})
+	// 校验冒号
+	c.skipSpace()
+	if c.peek() != ':' {
+		return c.fail("expected ':' after object key")
 	}
-	return in
+	c.pos++
+	if !c.value() {
+		return false
+	}
+	c.path = c.path[:len(c.path)-1]
+	return true
 }
 
 // 校验数组
-func validArray(data []byte, in *position) *position {
-	for ; in.pos < len(data); in.pos++ {
-		switch data[in.pos] {
-		default:
-			for ; in.pos < len(data); in.pos++ {
-				if in = validAny(data, in); !in.ok {
-					return in
-				}
-				if in = validComma(data, in, ']'); !in.ok {
-					return in
+func (c *checker) array() bool {
+	c.pos++
+	c.skipSpace()
+	if c.peek() == ']' {
+		c.pos++
+		return true
+	}
+	depth := len(c.path)
+	for index := 0; ; index++ {
+		c.path = append(c.path, segment{index: index})
+		if c.value() {
+			c.path = c.path[:depth]
+			// 校验逗号
+			c.skipSpace()
+			switch ch := c.peek(); {
+			case ch == ',':
+				c.pos++
+				if c.trailingComma(']') {
+					return true
 				}
-				if data[in.pos] == ']' {
-					return in.buildTrue(in.pos + 1)
+				continue
+			case ch == ']':
+				c.pos++
+				return true
+			default:
+				c.fail("expected ',' or ']' after array element")
+				// a missing comma, go on with the next element
+				if !c.abort && c.startsValue(ch) {
+					continue
 				}
 			}
-		case ' ', '\t', '\r':
-			continue
-		case '\n':
-			in.AddLine()
-			continue
-		case ']':
-			return in.buildTrue(in.pos + 1)
+		}
+		c.path = c.path[:depth]
+		if next, done := c.recover(']'); !next {
+			return done
 		}
 	}
-	return in.buildFalse()
 }
 
-func validString(data []byte, i int) (outi int, ok bool) {
-	for ; i < len(data); i++ {
-		if data[i] < ' ' {
-			return i, false
-		} else if data[i] == '\\' {
-			i++
-			if i == len(data) {
-				return i, false
-			}
-			switch data[i] {
-			default:
-				return i, false
+func (c *checker) startsValue(ch byte) bool {
+	switch ch {
+	case '{', '[', '"', '-', 't', 'f', 'n':
+		return true
+	case '\'':
+		return c.mode&AllowSingleQuotes != 0
+	}
+	return isDigit(ch)
+}
+
+// startsKey reports whether ch starts a key only allowed by the relaxed modes.
+func (c *checker) startsKey(ch byte) bool {
+	return (ch == '\'' && c.mode&AllowSingleQuotes != 0) ||
+		(isIdentStart(ch) && c.mode&AllowUnquotedKeys != 0)
+}
+
+func isIdentStart(ch byte) bool {
+	return ch == '_' || ch == '$' || (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z')
+}
+
+func isIdentPart(ch byte) bool {
+	return isIdentStart(ch) || isDigit(ch)
+}
+
+func (c *checker) string() bool {
+	quote := c.data[c.pos]
+	c.pos++
+	for ; c.pos < len(c.data); c.pos++ {
+		switch ch := c.data[c.pos]; {
+		case ch == quote:
+			c.pos++
+			return true
+		case ch == '\n':
+			return c.fail("unterminated string")
+		case ch < ' ':
+			return c.fail("invalid control character in string")
+		case ch == '\\':
+			c.pos++
+			switch c.peek() {
 			case '"', '\\', '/', 'b', 'f', 'n', 'r', 't':
+			case '\'':
+				if quote != '\'' {
+					return c.fail("invalid escape character in string")
+				}
 			case 'u':
 				for j := 0; j < 4; j++ {
-					i++
-					if i >= len(data) {
-						return i, false
-					}
-					if !((data[i] >= '0' && data[i] <= '9') ||
-						(data[i] >= 'a' && data[i] <= 'f') ||
-						(data[i] >= 'A' && data[i] <= 'F')) {
-						return i, false
+					c.pos++
+					if !isHex(c.peek()) {
+						return c.fail("expected hexadecimal digit in \\u escape")
 					}
 				}
+			default:
+				if c.pos >= len(c.data) {
+					return c.fail("unterminated string")
+				}
+				return c.fail("invalid escape character in string")
 			}
-		} else if data[i] == '"' {
-			return i + 1, true
 		}
 	}
-	return i, false
+	return c.fail("unterminated string")
 }
 
-func validNumber(data []byte, i int) (outi int, ok bool) {
-	i--
+func isHex(ch byte) bool {
+	return (ch >= '0' && ch <= '9') || (ch >= 'a' && ch <= 'f') || (ch >= 'A' && ch <= 'F')
+}
+
+func isDigit(ch byte) bool {
+	return ch >= '0' && ch <= '9'
+}
+
+func (c *checker) digits() {
+	for isDigit(c.peek()) {
+		c.pos++
+	}
+}
+
+func (c *checker) number() bool {
 	// sign
-	if data[i] == '-' {
-		i++
-		if i == len(data) {
-			return i, false
-		}
-		if data[i] < '0' || data[i] > '9' {
-			return i, false
+	if c.peek() == '-' {
+		c.pos++
+		if !isDigit(c.peek()) {
+			return c.fail("expected digit after '-'")
 		}
 	}
 	// int
-	if i == len(data) {
-		return i, false
-	}
-	if data[i] == '0' {
-		i++
+	if c.peek() == '0' {
+		c.pos++
 	} else {
-		for ; i < len(data); i++ {
-			if data[i] >= '0' && data[i] <= '9' {
-				continue
-			}
-			break
-		}
+		c.digits()
 	}
 	// frac
-	if i == len(data) {
-		return i, true
-	}
-	if data[i] == '.' {
-		i++
-		if i == len(data) {
-			return i, false
-		}
-		if data[i] < '0' || data[i] > '9' {
-			return i, false
-		}
-		i++
-		for ; i < len(data); i++ {
-			if data[i] >= '0' && data[i] <= '9' {
-				continue
-			}
-			break
+	if c.peek() == '.' {
+		c.pos++
+		if !isDigit(c.peek()) {
+			return c.fail("expected digit after decimal point")
 		}
+		c.digits()
 	}
 	// exp
-	if i == len(data) {
-		return i, true
-	}
-	if data[i] == 'e' || data[i] == 'E' {
-		i++
-		if i == len(data) {
-			return i, false
-		}
-		if data[i] == '+' || data[i] == '-' {
-			i++
-		}
-		if i == len(data) {
-			return i, false
+	if ch := c.peek(); ch == 'e' || ch == 'E' {
+		c.pos++
+		if ch = c.peek(); ch == '+' || ch == '-' {
+			c.pos++
 		}
-		if data[i] < '0' || data[i] > '9' {
-			return i, false
+		if !isDigit(c.peek()) {
+			return c.fail("expected digit in exponent")
 		}
-		i++
-		for ; i < len(data); i++ {
-			if data[i] >= '0' && data[i] <= '9' {
-				continue
-			}
-			break
-		}
-	}
-	return i, true
-}
-
-func validTrue(data []byte, i int) (outi int, ok bool) {
-	if i+3 <= len(data) && data[i] == 'r' && data[i+1] == 'u' &&
-		data[i+2] == 'e' {
-		return i + 3, true
+		c.digits()
 	}
-	return i, false
+	return true
 }
 
-func validFalse(data []byte, i int) (outi int, ok bool) {
-	if i+4 <= len(data) && data[i] == 'a' && data[i+1] == 'l' &&
-		data[i+2] == 's' && data[i+3] == 'e' {
-		return i + 4, true
-	}
-	return i, false
-}
-
-func validNull(data []byte, i int) (outi int, ok bool) {
-	if i+3 <= len(data) && data[i] == 'u' && data[i+1] == 'l' &&
-		data[i+2] == 'l' {
-		return i + 3, true
+func (c *checker) literal(lit string) bool {
+	for i := 0; i < len(lit); i++ {
+		if c.peek() != lit[i] {
+			return c.fail("invalid literal, expected '" + lit + "'")
+		}
+		c.pos++
 	}
-	return i, false
+	return true
 }
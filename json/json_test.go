@@ -4,6 +4,7 @@
 package json
 
 import (
+	"reflect"
 	"testing"
 )
 
@@ -27,12 +28,43 @@ func Test_validPayload(t *testing.T) {
 		wantOk   bool
 	}{
 		{
-			name: "err",
+			name: "valid",
 			args: args{
 				data: []byte(errJsonStr),
+				l:    1,
+			},
+			wantLine: 5,
+			wantOuti: 1,
+			wantOk:   true,
+		},
+		{
+			name: "missing-colon",
+			args: args{
+				data: []byte("{\n\"a\" 1}"),
+				l:    1,
+			},
+			wantLine: 2,
+			wantOuti: 4,
+			wantOk:   false,
+		},
+		{
+			name: "array-element",
+			args: args{
+				data: []byte("[1,\n  x]"),
+				l:    1,
 			},
 			wantLine: 2,
-			wantOuti: 0,
+			wantOuti: 2,
+			wantOk:   false,
+		},
+		{
+			name: "first-line",
+			args: args{
+				data: []byte(`{"a": tru}`),
+				l:    1,
+			},
+			wantLine: 1,
+			wantOuti: 9,
 			wantOk:   false,
 		},
 	}
@@ -51,3 +83,206 @@ func Test_validPayload(t *testing.T) {
 		})
 	}
 }
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+		want    *SyntaxError
+	}{
+		{
+			name:    "valid",
+			payload: errJsonStr,
+		},
+		{
+			name:    "missing-colon",
+			payload: "{\n\"a\" 1}",
+			want:    &SyntaxError{Msg: "expected ':' after object key", Line: 2, Column: 5, Offset: 6, Char: '1', Path: "$.a"},
+		},
+		{
+			name:    "array-element",
+			payload: `{"a": 1, "b": [2, tru]}`,
+			want:    &SyntaxError{Msg: "invalid literal, expected 'true'", Line: 1, Column: 22, Offset: 21, Char: ']', Path: "$.b[1]"},
+		},
+		{
+			name:    "quoted-key",
+			payload: `{"a b": [{"c": -x}]}`,
+			want:    &SyntaxError{Msg: "expected digit after '-'", Line: 1, Column: 17, Offset: 16, Char: 'x', Path: `$["a b"][0].c`},
+		},
+		{
+			name:    "unterminated-string",
+			payload: `["abc`,
+			want:    &SyntaxError{Msg: "unterminated string", Line: 1, Column: 6, Offset: 5, EOF: true, Path: "$[0]"},
+		},
+		{
+			name:    "trailing-data",
+			payload: `{} x`,
+			want:    &SyntaxError{Msg: "expected end of input after top-level value", Line: 1, Column: 4, Offset: 3, Char: 'x', Path: "$"},
+		},
+		{
+			name:    "missing-comma",
+			payload: `{"a": 1 "b": 2}`,
+			want:    &SyntaxError{Msg: "expected ',' or '}' after object value", Line: 1, Column: 9, Offset: 8, Char: '"', Path: "$"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate([]byte(tt.payload))
+			if tt.want == nil {
+				if err != nil {
+					t.Errorf("Validate() error = %v, want nil", err)
+				}
+				return
+			}
+			got, ok := err.(*SyntaxError)
+			if !ok {
+				t.Fatalf("Validate() error = %v, want *SyntaxError", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Validate() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckBytes(t *testing.T) {
+	line, pos, ok := CheckBytes([]byte("{\n  \"a\" 1\n}"))
+	if line != 2 || pos != 6 || ok {
+		t.Errorf("CheckBytes() = %v, %v, %v, want 2, 6, false", line, pos, ok)
+	}
+	if err := Validate([]byte(`[1, ]`)); err == nil || err.Error() != "1:5: expected value, found ']' at $[1]" {
+		t.Errorf("Validate() error = %v", err)
+	}
+}
+
+func TestValidateAll(t *testing.T) {
+	const payload = `{
+  "a": 1
+  "b": tru,
+  "c": [1, x, 3],
+  "d": "abc
+  "e": {"x" 1
+  },
+}`
+	want := []string{
+		"3:3: expected ',' or '}' after object value, found '\"' at $",
+		"3:11: invalid literal, expected 'true', found ',' at $.b",
+		"4:12: expected value, found 'x' at $.c[1]",
+		"5:12: unterminated string, found '\\n' at $.d",
+		"6:13: expected ':' after object key, found '1' at $.e.x",
+		"8:1: expected string for object key, found '}' at $",
+	}
+	tests := []struct {
+		name string
+		max  int
+		want []string
+	}{
+		{name: "all", max: 0, want: want},
+		{name: "limited", max: 2, want: want[:2]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, err := range ValidateAll([]byte(payload), tt.max) {
+				got = append(got, err.Error())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ValidateAll() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+	if errs := ValidateAll([]byte(errJsonStr), 0); len(errs) != 0 {
+		t.Errorf("ValidateAll() = %v, want none", errs)
+	}
+}
+
+const jsoncStr = `// service config
+{
+	/* listen address */
+	"addr": "127.0.0.1", // trailing comment
+	"ports": [8001, 8002,],
+}`
+
+const json5Str = `{
+	name: 'zc\'s "demo"',
+	$tags: ['a', "b",],
+}`
+
+func TestValidateMode(t *testing.T) {
+	if err := ValidateMode([]byte(json5Str), JSON5); err != nil {
+		t.Errorf("ValidateMode() error = %v", err)
+	}
+	err := ValidateMode([]byte("{/* open"), JSONC)
+	if err == nil || err.Error() != "1:9: expected string for object key, found end of input at $" {
+		t.Errorf("ValidateMode() error = %v", err)
+	}
+	err = ValidateMode([]byte("[1,]"), AllowComments)
+	if err == nil || err.Error() != "1:4: expected value, found ']' at $[1]" {
+		t.Errorf("ValidateMode() error = %v", err)
+	}
+	if err := ValidateMode([]byte("{a: 1}\n// next\n{b: 2,} 3"), JSON5|AllowValueStream); err != nil {
+		t.Errorf("ValidateMode() error = %v", err)
+	}
+	err = ValidateMode([]byte("{}\n{a: 1}"), JSON5)
+	if err == nil || err.Error() != "2:1: expected end of input after top-level value, found '{' at $" {
+		t.Errorf("ValidateMode() error = %v", err)
+	}
+}
+
+func TestStandardize(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+		mode    Mode
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "jsonc",
+			payload: jsoncStr,
+			mode:    JSONC,
+			want:    "\n{\n\t\n\t\"addr\": \"127.0.0.1\", \n\t\"ports\": [8001, 8002]\n}",
+			wantErr: false,
+		},
+		{
+			name:    "json5",
+			payload: json5Str,
+			mode:    JSON5,
+			want:    "{\n\t\"name\": \"zc's \\\"demo\\\"\",\n\t\"$tags\": [\"a\", \"b\"]\n}",
+			wantErr: false,
+		},
+		{
+			name:    "json5-stream",
+			payload: "{a: 1,}\n['b']",
+			mode:    JSON5 | AllowValueStream,
+			want:    "{\"a\": 1}\n[\"b\"]",
+			wantErr: false,
+		},
+		{
+			name:    "jsonc-rejects-json5",
+			payload: json5Str,
+			mode:    JSONC,
+			wantErr: true,
+		},
+		{
+			name:    "strict-rejects-jsonc",
+			payload: jsoncStr,
+			mode:    Strict,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Standardize([]byte(tt.payload), tt.mode)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Standardize() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if string(got) != tt.want {
+				t.Errorf("Standardize() got = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
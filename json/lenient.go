@@ -0,0 +1,168 @@
+// Package json
+// Copyright © 2021 zc2638 <zc2638@qq.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package json
+
+// Mode enables relaxations of the JSON grammar.
+type Mode uint
+
+const (
+	// AllowComments accepts // line and /* block */ comments wherever whitespace is allowed.
+	AllowComments Mode = 1 << iota
+	// AllowTrailingCommas accepts a comma after the last member or element.
+	AllowTrailingCommas
+	// AllowUnquotedKeys accepts identifiers as object keys.
+	AllowUnquotedKeys
+	// AllowSingleQuotes accepts single-quoted strings.
+	AllowSingleQuotes
+	// AllowValueStream accepts a stream of top-level values separated by optional whitespace.
+	AllowValueStream
+)
+
+const (
+	// Strict accepts standard JSON only.
+	Strict Mode = 0
+	// JSONC accepts JSON with comments and trailing commas.
+	JSONC = AllowComments | AllowTrailingCommas
+	// JSON5 additionally accepts unquoted keys and single-quoted strings.
+	JSON5 = JSONC | AllowUnquotedKeys | AllowSingleQuotes
+)
+
+// Standardize checks payload with the relaxations enabled by mode
+// and rewrites it as standard JSON.
+// Comments and trailing commas are dropped, unquoted keys and
+// single-quoted strings are turned into double-quoted strings.
+func Standardize(payload []byte, mode Mode) ([]byte, error) {
+	if err := ValidateMode(payload, mode); err != nil {
+		return nil, err
+	}
+	if mode == Strict {
+		return payload, nil
+	}
+	out := make([]byte, 0, len(payload))
+	// objects records, for each open object or array, whether it is an object
+	var objects []bool
+	expectKey := false
+	for i := 0; i < len(payload); i++ {
+		switch ch := payload[i]; {
+		case ch == '/' && i+1 < len(payload) && (payload[i+1] == '/' || payload[i+1] == '*'):
+			i = skipComment(payload, i)
+		case ch == '"' || ch == '\'':
+			end := skipString(payload, i)
+			if ch == '"' {
+				out = append(out, payload[i:end]...)
+			} else {
+				out = requote(out, payload[i:end])
+			}
+			i = end - 1
+		case ch == '{' || ch == '[':
+			objects = append(objects, ch == '{')
+			expectKey = ch == '{'
+			out = append(out, ch)
+		case ch == '}' || ch == ']':
+			objects = objects[:len(objects)-1]
+			out = append(out, ch)
+		case ch == ',':
+			if next := skipSpaceAndComments(payload, i+1); next < len(payload) &&
+				(payload[next] == '}' || payload[next] == ']') {
+				// drop the trailing comma
+				continue
+			}
+			expectKey = len(objects) > 0 && objects[len(objects)-1]
+			out = append(out, ch)
+		case ch == ':':
+			expectKey = false
+			out = append(out, ch)
+		case expectKey && isIdentStart(ch):
+			end := i + 1
+			for end < len(payload) && isIdentPart(payload[end]) {
+				end++
+			}
+			out = append(out, '"')
+			out = append(out, payload[i:end]...)
+			out = append(out, '"')
+			i = end - 1
+		default:
+			out = append(out, ch)
+		}
+	}
+	return out, nil
+}
+
+// skipComment returns the index of the last byte of the comment starting at i.
+func skipComment(data []byte, i int) int {
+	if data[i+1] == '/' {
+		for i += 2; i < len(data) && data[i] != '\n'; i++ {
+		}
+		// keep the newline
+		return i - 1
+	}
+	for i += 2; i+1 < len(data); i++ {
+		if data[i] == '*' && data[i+1] == '/' {
+			return i + 1
+		}
+	}
+	return len(data) - 1
+}
+
+// skipString returns the index following the string starting at i.
+func skipString(data []byte, i int) int {
+	quote := data[i]
+	for i++; i < len(data); i++ {
+		switch data[i] {
+		case '\\':
+			i++
+		case quote:
+			return i + 1
+		}
+	}
+	return i
+}
+
+func skipSpaceAndComments(data []byte, i int) int {
+	for ; i < len(data); i++ {
+		switch data[i] {
+		case ' ', '\t', '\r', '\n':
+		case '/':
+			if i+1 >= len(data) || (data[i+1] != '/' && data[i+1] != '*') {
+				return i
+			}
+			i = skipComment(data, i)
+		default:
+			return i
+		}
+	}
+	return i
+}
+
+// requote appends the single-quoted string s to dst as a double-quoted string.
+func requote(dst, s []byte) []byte {
+	dst = append(dst, '"')
+	for i := 1; i < len(s)-1; i++ {
+		switch s[i] {
+		case '\\':
+			if s[i+1] == '\'' {
+				dst = append(dst, '\'')
+			} else {
+				dst = append(dst, s[i], s[i+1])
+			}
+			i++
+		case '"':
+			dst = append(dst, '\\', '"')
+		default:
+			dst = append(dst, s[i])
+		}
+	}
+	return append(dst, '"')
+}
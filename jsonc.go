@@ -0,0 +1,65 @@
+// Package ditto
+// Copyright © 2021 zc2638 <zc2638@qq.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package ditto
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+
+	jsone "github.com/99nil/ditto/json"
+)
+
+// lenientUnmarshal returns an UnmarshalFunc for JSON relaxed by mode,
+// the data is standardized before being decoded as JSON.
+func lenientUnmarshal(mode jsone.Mode) UnmarshalFunc {
+	return func(data []byte, v interface{}) error {
+		data, err := jsone.Standardize(data, mode)
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal(data, v)
+	}
+}
+
+// lenientDecoder decodes a stream of JSON values relaxed by mode.
+// The relaxed syntax cannot be standardized piecemeal,
+// so the whole input is read on the first call of Decode.
+type lenientDecoder struct {
+	r    io.Reader
+	mode jsone.Mode
+	dec  *json.Decoder
+}
+
+func newLenientDecoder(mode jsone.Mode) DecoderFunc {
+	return func(r io.Reader) Decoder {
+		return &lenientDecoder{r: r, mode: mode}
+	}
+}
+
+func (d *lenientDecoder) Decode(v interface{}) error {
+	if d.dec == nil {
+		data, err := ioutil.ReadAll(d.r)
+		if err != nil {
+			return err
+		}
+		if data, err = jsone.Standardize(data, d.mode|jsone.AllowValueStream); err != nil {
+			return err
+		}
+		d.dec = json.NewDecoder(bytes.NewReader(data))
+	}
+	return d.dec.Decode(v)
+}
@@ -0,0 +1,222 @@
+// Package ndjson
+// Copyright © 2021 zc2638 <zc2638@qq.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package ndjson
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	jsone "github.com/99nil/ditto/json"
+)
+
+// Marshal returns the records of v, the members of an array or v itself, one per line.
+func Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Unmarshal decodes the records of data, newline-delimited JSON values also known as JSON Lines,
+// as a JSON array into v. Blank lines are skipped, and lines may end with "\r\n".
+// Syntax errors are *json.SyntaxError values of the ditto json package,
+// positioned in data rather than in the record.
+func Unmarshal(data []byte, v interface{}) error {
+	var array bytes.Buffer
+	array.WriteByte('[')
+	err := scan(data, func(record []byte) {
+		if array.Len() > 1 {
+			array.WriteByte(',')
+		}
+		array.Write(record)
+	})
+	if err != nil {
+		return err
+	}
+	array.WriteByte(']')
+	return json.Unmarshal(array.Bytes(), v)
+}
+
+// ValidateAll checks the records of data and returns up to max syntax errors,
+// or all of them when max <= 0, positioned in data.
+func ValidateAll(data []byte, max int) []*jsone.SyntaxError {
+	var errs []*jsone.SyntaxError
+	r := newReader(bytes.NewReader(data))
+	for max <= 0 || len(errs) < max {
+		record, err := r.next()
+		if err != nil {
+			break
+		}
+		left := max - len(errs)
+		if max <= 0 {
+			left = 0
+		}
+		for _, se := range jsone.ValidateAll(record, left) {
+			errs = append(errs, r.locate(se))
+		}
+	}
+	return errs
+}
+
+// scan calls fn with every valid record of data.
+func scan(data []byte, fn func(record []byte)) error {
+	r := newReader(bytes.NewReader(data))
+	for {
+		record, err := r.next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := r.check(record); err != nil {
+			return err
+		}
+		fn(record)
+	}
+}
+
+// reader reads the records of a stream line by line.
+type reader struct {
+	r *bufio.Reader
+	// line is the 1-based line of the last record
+	line int
+	// offset and column are those of the first byte of the last record
+	offset int
+	column int
+	// next byte offset to read
+	pos int
+}
+
+func newReader(r io.Reader) *reader {
+	return &reader{r: bufio.NewReader(r)}
+}
+
+// next returns the next record without its surrounding whitespace, or io.EOF.
+func (r *reader) next() ([]byte, error) {
+	for {
+		data, err := r.r.ReadBytes('\n')
+		if len(data) == 0 {
+			return nil, err
+		}
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		r.line++
+		start := r.pos
+		r.pos += len(data)
+		trimmed := data
+		if r.line == 1 {
+			trimmed = bytes.TrimPrefix(trimmed, []byte("\ufeff"))
+		}
+		trimmed = bytes.TrimLeft(trimmed, " \t\r\n")
+		record := bytes.TrimRight(trimmed, " \t\r\n")
+		if len(record) == 0 {
+			continue
+		}
+		lead := len(data) - len(trimmed)
+		r.offset, r.column = start+lead, lead+1
+		return record, nil
+	}
+}
+
+// check returns the first syntax error of the last record, if any, positioned in the stream.
+func (r *reader) check(record []byte) error {
+	err := jsone.Validate(record)
+	var se *jsone.SyntaxError
+	if errors.As(err, &se) {
+		return r.locate(se)
+	}
+	return err
+}
+
+// locate positions a syntax error of the last record in the stream.
+func (r *reader) locate(se *jsone.SyntaxError) *jsone.SyntaxError {
+	located := *se
+	located.Line = r.line
+	located.Column = se.Column + r.column - 1
+	located.Offset = se.Offset + r.offset
+	return &located
+}
+
+// Decoder reads records from an input stream, one per call to Decode,
+// holding a single line in memory at a time.
+type Decoder struct {
+	r *reader
+}
+
+// NewDecoder returns a new decoder that reads from r.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: newReader(r)}
+}
+
+// Decode reads the next record and stores it in v like encoding/json,
+// or returns io.EOF when there are no more records.
+// Syntax errors are *json.SyntaxError values of the ditto json package, positioned in the stream.
+func (dec *Decoder) Decode(v interface{}) error {
+	record, err := dec.r.next()
+	if err != nil {
+		return err
+	}
+	if err := dec.r.check(record); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(record, v); err != nil {
+		return fmt.Errorf("line %d: %w", dec.r.line, err)
+	}
+	return nil
+}
+
+// Encoder writes records to an output stream, one per call to Encode.
+type Encoder struct {
+	w io.Writer
+}
+
+// NewEncoder returns a new encoder that writes to w.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: w}
+}
+
+// Encode writes the records of v like Marshal, each as a compact JSON value followed by a line feed.
+// Unlike encoding/json, the characters <, > and & are not escaped.
+func (enc *Encoder) Encode(v interface{}) error {
+	records, ok := v.([]interface{})
+	if !ok {
+		return enc.record(v)
+	}
+	for _, record := range records {
+		if err := enc.record(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// record writes v as a single record.
+func (enc *Encoder) record(v interface{}) error {
+	var buf bytes.Buffer
+	e := json.NewEncoder(&buf)
+	e.SetEscapeHTML(false)
+	if err := e.Encode(v); err != nil {
+		return err
+	}
+	_, err := enc.w.Write(buf.Bytes())
+	return err
+}
@@ -0,0 +1,148 @@
+// Package ndjson
+// Copyright © 2021 zc2638 <zc2638@qq.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package ndjson
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	jsone "github.com/99nil/ditto/json"
+	"github.com/99nil/ditto/ordered"
+)
+
+const logs = `{"level":"info","msg":"start"}
+
+{"level":"warn","msg":"<slow>","ms":1200}` + "\r\n" + `  ["a",1]
+`
+
+func TestDecoder_Decode(t *testing.T) {
+	want := []interface{}{
+		map[string]interface{}{"level": "info", "msg": "start"},
+		map[string]interface{}{"level": "warn", "msg": "<slow>", "ms": float64(1200)},
+		[]interface{}{"a", float64(1)},
+	}
+	dec := NewDecoder(strings.NewReader(logs))
+	var got []interface{}
+	for {
+		var v interface{}
+		err := dec.Decode(&v)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Decode() error = %v", err)
+		}
+		got = append(got, v)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Decode() = %#v, want %#v", got, want)
+	}
+
+	var all interface{}
+	if err := Unmarshal([]byte(logs), &all); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if !reflect.DeepEqual(all, want) {
+		t.Errorf("Unmarshal() = %#v, want %#v", all, want)
+	}
+
+	var ov ordered.Value
+	if err := NewDecoder(strings.NewReader(`{"b":1,"a":2}`)).Decode(&ov); err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if keys := ov.V.(ordered.Map).Keys(); !reflect.DeepEqual(keys, []string{"b", "a"}) {
+		t.Errorf("Decode() keys = %v", keys)
+	}
+}
+
+func TestDecoder_SyntaxError(t *testing.T) {
+	tests := []struct {
+		name       string
+		data       string
+		wantLine   int
+		wantColumn int
+		wantOffset int
+	}{
+		{name: "first line", data: `{"a":}`, wantLine: 1, wantColumn: 6, wantOffset: 5},
+		{name: "after blank line", data: "{}\n\n  {\"a\" 1}\n", wantLine: 3, wantColumn: 8, wantOffset: 11},
+		{name: "two values on a line", data: "{}\n{} {}\n", wantLine: 2, wantColumn: 4, wantOffset: 6},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dec := NewDecoder(strings.NewReader(tt.data))
+			var err error
+			for err == nil {
+				var v interface{}
+				err = dec.Decode(&v)
+			}
+			var se *jsone.SyntaxError
+			if !errors.As(err, &se) {
+				t.Fatalf("Decode() error = %v, want *json.SyntaxError", err)
+			}
+			if se.Line != tt.wantLine || se.Column != tt.wantColumn || se.Offset != tt.wantOffset {
+				t.Errorf("Decode() error at %d:%d offset %d, want %d:%d offset %d",
+					se.Line, se.Column, se.Offset, tt.wantLine, tt.wantColumn, tt.wantOffset)
+			}
+
+			var v interface{}
+			if err := Unmarshal([]byte(tt.data), &v); !errors.As(err, &se) || se.Line != tt.wantLine {
+				t.Errorf("Unmarshal() error = %v, want line %d", err, tt.wantLine)
+			}
+		})
+	}
+}
+
+func TestValidateAll(t *testing.T) {
+	errs := ValidateAll([]byte("{\"a\":}\n{}\n[1,]\n"), 0)
+	if len(errs) != 2 || errs[0].Line != 1 || errs[1].Line != 3 {
+		t.Errorf("ValidateAll() = %v, want errors on lines 1 and 3", errs)
+	}
+	if errs := ValidateAll([]byte("x\ny\n"), 1); len(errs) != 1 {
+		t.Errorf("ValidateAll() = %v, want 1 error", errs)
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	got, err := Marshal([]interface{}{
+		ordered.Map{{Key: "b", Value: "x"}, {Key: "a", Value: []interface{}{1, 2}}},
+		[]interface{}{"x"},
+	})
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if want := "{\"b\":\"x\",\"a\":[1,2]}\n[\"x\"]\n"; string(got) != want {
+		t.Errorf("Marshal() = %s, want %s", got, want)
+	}
+
+	var buf bytes.Buffer
+	if err := NewEncoder(&buf).Encode(map[string]interface{}{"a": map[string]interface{}{"b": "<b>"}}); err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	if want := "{\"a\":{\"b\":\"<b>\"}}\n"; buf.String() != want {
+		t.Errorf("Encode() = %s, want %s", buf.String(), want)
+	}
+
+	buf.Reset()
+	if err := NewEncoder(&buf).Encode([]interface{}{1, []interface{}{"x"}}); err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	if want := "1\n[\"x\"]\n"; buf.String() != want {
+		t.Errorf("Encode() = %s, want %s", buf.String(), want)
+	}
+}
@@ -0,0 +1,359 @@
+// Package ditto
+// Copyright © 2021 zc2638 <zc2638@qq.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package ditto
+
+import (
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/99nil/ditto/ordered"
+)
+
+// orderedFormats decode into an ordered.Value,
+// their unmarshal functions honor json.Unmarshaler or yaml.Unmarshaler,
+// or decode into an ordered.Value themselves.
+var orderedFormats = map[string]bool{
+	FormatJSON:  true,
+	FormatJSONC: true,
+	FormatJSON5: true,
+	FormatYaml:  true,
+	FormatINI:   true,
+
+	FormatProperties: true,
+	FormatNDJSON:     true,
+}
+
+var interfaceType = reflect.TypeOf((*interface{})(nil)).Elem()
+
+// tomlValue converts ordered maps to structs,
+// since the TOML encoder sorts map keys but keeps the order of struct fields.
+func tomlValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case ordered.Map:
+		if !taggable(val) {
+			// the order of this level is lost, but not its keys
+			m := make(map[string]interface{}, len(val))
+			for _, item := range val {
+				if item.Value != nil {
+					m[item.Key] = tomlValue(item.Value)
+				}
+			}
+			return m
+		}
+		fields := make([]reflect.StructField, 0, len(val))
+		values := make([]interface{}, 0, len(val))
+		for _, item := range val {
+			if item.Value == nil {
+				continue
+			}
+			fields = append(fields, reflect.StructField{
+				Name: "F" + strconv.Itoa(len(fields)),
+				Type: interfaceType,
+				Tag:  reflect.StructTag("toml:" + strconv.Quote(item.Key)),
+			})
+			values = append(values, tomlValue(item.Value))
+		}
+		st := reflect.New(reflect.StructOf(fields)).Elem()
+		for i, value := range values {
+			st.Field(i).Set(reflect.ValueOf(value))
+		}
+		return st.Interface()
+	case map[string]interface{}:
+		// plain maps may hold ordered ones, e.g. the wrapper of several documents
+		m := make(map[string]interface{}, len(val))
+		for k, elem := range val {
+			m[k] = tomlValue(elem)
+		}
+		return m
+	case []interface{}:
+		arr := make([]interface{}, 0, len(val))
+		for _, elem := range val {
+			arr = append(arr, tomlValue(elem))
+		}
+		return arr
+	}
+	return v
+}
+
+// taggable reports whether every key of m survives as a toml struct tag,
+// which names no field when empty or "-" and ends at the first comma.
+func taggable(m ordered.Map) bool {
+	for _, item := range m {
+		if item.Key == "" || item.Key == "-" || strings.Contains(item.Key, ",") {
+			return false
+		}
+	}
+	return true
+}
+
+// orderTOML orders the keys of a decoded TOML document as they appear in data.
+func orderTOML(data []byte, v map[string]interface{}) ordered.Map {
+	s := &tomlScanner{data: data, order: make(map[string]int)}
+	s.scan()
+	return orderMap(v, "", s.order)
+}
+
+func orderMap(data map[string]interface{}, path string, order map[string]int) ordered.Map {
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	// keys missing from order keep the alphabetical order at the end
+	sort.Strings(keys)
+	index := func(key string) int {
+		if i, ok := order[path+key]; ok {
+			return i
+		}
+		return math.MaxInt32
+	}
+	sort.SliceStable(keys, func(i, j int) bool {
+		return index(keys[i]) < index(keys[j])
+	})
+	m := make(ordered.Map, 0, len(keys))
+	for _, key := range keys {
+		m = append(m, ordered.Item{Key: key, Value: orderValue(data[key], path+key+"\x00", order)})
+	}
+	return m
+}
+
+func orderValue(v interface{}, path string, order map[string]int) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		return orderMap(val, path, order)
+	case []interface{}:
+		arr := make([]interface{}, 0, len(val))
+		for _, elem := range val {
+			arr = append(arr, orderValue(elem, path, order))
+		}
+		return arr
+	}
+	return v
+}
+
+// tomlScanner records the first appearance of every key path of a TOML document,
+// the key parts being joined by NUL.
+// It only runs on documents that decoded successfully, so it skips values loosely.
+type tomlScanner struct {
+	data  []byte
+	pos   int
+	order map[string]int
+}
+
+func (s *tomlScanner) peek() byte {
+	if s.pos < len(s.data) {
+		return s.data[s.pos]
+	}
+	return 0
+}
+
+func (s *tomlScanner) record(path []string) {
+	for i := 1; i <= len(path); i++ {
+		key := strings.Join(path[:i], "\x00")
+		if _, ok := s.order[key]; !ok {
+			s.order[key] = len(s.order)
+		}
+	}
+}
+
+func (s *tomlScanner) scan() {
+	var table []string
+	for {
+		s.skipBlank()
+		if s.pos >= len(s.data) {
+			return
+		}
+		start := s.pos
+		if s.peek() == '[' {
+			s.pos++
+			if s.peek() == '[' {
+				s.pos++
+			}
+			table = s.keys()
+			s.record(table)
+		} else if path := s.keys(); len(path) > 0 {
+			full := append(append([]string(nil), table...), path...)
+			s.record(full)
+			s.skipSpace()
+			if s.peek() == '=' {
+				s.pos++
+				s.value(full)
+			}
+		}
+		s.skipLine()
+		if s.pos == start {
+			s.pos++
+		}
+	}
+}
+
+func (s *tomlScanner) skipSpace() {
+	for s.peek() == ' ' || s.peek() == '\t' {
+		s.pos++
+	}
+}
+
+// skipBlank skips whitespace, newlines and comments.
+func (s *tomlScanner) skipBlank() {
+	for s.pos < len(s.data) {
+		switch s.data[s.pos] {
+		case ' ', '\t', '\r', '\n':
+			s.pos++
+		case '#':
+			s.skipLine()
+		default:
+			return
+		}
+	}
+}
+
+func (s *tomlScanner) skipLine() {
+	for s.pos < len(s.data) && s.data[s.pos] != '\n' {
+		s.pos++
+	}
+}
+
+// keys reads a dotted key.
+func (s *tomlScanner) keys() []string {
+	var path []string
+	for {
+		s.skipSpace()
+		var key string
+		switch s.peek() {
+		case '"':
+			start := s.pos
+			s.skipString()
+			raw := string(s.data[start:s.pos])
+			if unquoted, err := strconv.Unquote(raw); err == nil {
+				key = unquoted
+			} else {
+				key = strings.Trim(raw, `"`)
+			}
+		case '\'':
+			start := s.pos
+			s.skipString()
+			key = strings.Trim(string(s.data[start:s.pos]), "'")
+		default:
+			start := s.pos
+			for ch := s.peek(); ch == '_' || ch == '-' || isAlnum(ch); ch = s.peek() {
+				s.pos++
+			}
+			if s.pos == start {
+				return path
+			}
+			key = string(s.data[start:s.pos])
+		}
+		path = append(path, key)
+		s.skipSpace()
+		if s.peek() != '.' {
+			return path
+		}
+		s.pos++
+	}
+}
+
+func isAlnum(ch byte) bool {
+	return (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z') || (ch >= '0' && ch <= '9')
+}
+
+// value skips a value, recording the keys of its inline tables under path.
+func (s *tomlScanner) value(path []string) {
+	s.skipSpace()
+	switch s.peek() {
+	case '{':
+		s.pos++
+		for s.pos < len(s.data) {
+			s.skipBlank()
+			if s.peek() == '}' {
+				s.pos++
+				return
+			}
+			keys := s.keys()
+			if len(keys) == 0 {
+				s.pos++
+				continue
+			}
+			full := append(append([]string(nil), path...), keys...)
+			s.record(full)
+			s.skipSpace()
+			if s.peek() == '=' {
+				s.pos++
+				s.value(full)
+			}
+			s.skipBlank()
+			if s.peek() == ',' {
+				s.pos++
+			}
+		}
+	case '[':
+		s.pos++
+		for s.pos < len(s.data) {
+			s.skipBlank()
+			if s.peek() == ']' {
+				s.pos++
+				return
+			}
+			start := s.pos
+			s.value(path)
+			s.skipBlank()
+			if s.peek() == ',' {
+				s.pos++
+			}
+			if s.pos == start {
+				s.pos++
+			}
+		}
+	case '"', '\'':
+		s.skipString()
+	default:
+		for s.pos < len(s.data) {
+			switch s.data[s.pos] {
+			case ',', ']', '}', '\n', '#':
+				return
+			}
+			s.pos++
+		}
+	}
+}
+
+// skipString skips a basic, literal or multi-line string.
+func (s *tomlScanner) skipString() {
+	quote := s.data[s.pos]
+	delim := []byte{quote}
+	if s.pos+2 < len(s.data) && s.data[s.pos+1] == quote && s.data[s.pos+2] == quote {
+		delim = []byte{quote, quote, quote}
+	}
+	s.pos += len(delim)
+	for s.pos < len(s.data) {
+		if quote == '"' && s.data[s.pos] == '\\' {
+			s.pos += 2
+			continue
+		}
+		if len(delim) == 1 && s.data[s.pos] == '\n' {
+			return
+		}
+		if strings.HasPrefix(string(s.data[s.pos:]), string(delim)) {
+			s.pos += len(delim)
+			// a multi-line string may end with up to two more quotes
+			for len(delim) == 3 && s.peek() == quote {
+				s.pos++
+			}
+			return
+		}
+		s.pos++
+	}
+}
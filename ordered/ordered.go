@@ -0,0 +1,224 @@
+// Package ordered
+// Copyright © 2021 zc2638 <zc2638@qq.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package ordered
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Item is a key and its value in a Map.
+type Item struct {
+	Key   string
+	Value interface{}
+}
+
+// Map is a map that keeps the order of its keys.
+// Nested objects are Maps too, arrays are []interface{}.
+type Map []Item
+
+// Get returns the value of key.
+func (m Map) Get(key string) (interface{}, bool) {
+	for _, item := range m {
+		if item.Key == key {
+			return item.Value, true
+		}
+	}
+	return nil, false
+}
+
+// Set replaces the value of key in place, or appends key when it is missing.
+func (m *Map) Set(key string, value interface{}) {
+	for i := range *m {
+		if (*m)[i].Key == key {
+			(*m)[i].Value = value
+			return
+		}
+	}
+	*m = append(*m, Item{Key: key, Value: value})
+}
+
+// Keys returns the keys of m in order.
+func (m Map) Keys() []string {
+	keys := make([]string, 0, len(m))
+	for _, item := range m {
+		keys = append(keys, item.Key)
+	}
+	return keys
+}
+
+func (m Map) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, item := range m {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(item.Key)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		value, err := json.Marshal(item.Value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func (m Map) MarshalYAML() (interface{}, error) {
+	ms := make(yaml.MapSlice, 0, len(m))
+	for _, item := range m {
+		ms = append(ms, yaml.MapItem{Key: item.Key, Value: item.Value})
+	}
+	return ms, nil
+}
+
+// Value decodes any document keeping the order of object keys.
+// V holds a Map, a []interface{} or a scalar.
+type Value struct {
+	V interface{}
+}
+
+func (v *Value) UnmarshalJSON(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	val, err := decodeJSON(dec)
+	if err != nil {
+		return err
+	}
+	v.V = val
+	return nil
+}
+
+func decodeJSON(dec *json.Decoder) (interface{}, error) {
+	token, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch token {
+	case json.Delim('{'):
+		m := Map{}
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			val, err := decodeJSON(dec)
+			if err != nil {
+				return nil, err
+			}
+			m.Set(key.(string), val)
+		}
+		_, err = dec.Token()
+		return m, err
+	case json.Delim('['):
+		arr := make([]interface{}, 0)
+		for dec.More() {
+			val, err := decodeJSON(dec)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, val)
+		}
+		_, err = dec.Token()
+		return arr, err
+	}
+	return token, nil
+}
+
+func (v *Value) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var val interface{}
+	if err := unmarshal(&val); err != nil {
+		return err
+	}
+	switch val.(type) {
+	case map[interface{}]interface{}:
+		var ms yaml.MapSlice
+		if err := unmarshal(&ms); err != nil {
+			return err
+		}
+		m, err := fromYaml(ms)
+		if err != nil {
+			return err
+		}
+		v.V = m
+	case []interface{}:
+		var seq []Value
+		if err := unmarshal(&seq); err != nil {
+			return err
+		}
+		arr := make([]interface{}, 0, len(seq))
+		for _, elem := range seq {
+			arr = append(arr, elem.V)
+		}
+		v.V = arr
+	default:
+		v.V = val
+	}
+	return nil
+}
+
+// fromYaml converts the maps decoded as yaml.MapSlice to Maps.
+func fromYaml(v interface{}) (interface{}, error) {
+	switch val := v.(type) {
+	case yaml.MapSlice:
+		m := make(Map, 0, len(val))
+		for _, item := range val {
+			key, err := keyString(item.Key)
+			if err != nil {
+				return nil, err
+			}
+			value, err := fromYaml(item.Value)
+			if err != nil {
+				return nil, err
+			}
+			m.Set(key, value)
+		}
+		return m, nil
+	case []interface{}:
+		for i := range val {
+			elem, err := fromYaml(val[i])
+			if err != nil {
+				return nil, err
+			}
+			val[i] = elem
+		}
+	}
+	return v, nil
+}
+
+func keyString(k interface{}) (string, error) {
+	switch val := k.(type) {
+	case string:
+		return val, nil
+	case int:
+		return strconv.Itoa(val), nil
+	case bool:
+		return strconv.FormatBool(val), nil
+	case nil:
+		return "null", nil
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64), nil
+	}
+	return "", fmt.Errorf("type mismatch: expect map key string or int; got: %T", k)
+}
@@ -0,0 +1,68 @@
+// Package ordered
+// Copyright © 2021 zc2638 <zc2638@qq.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package ordered
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+func TestValue_UnmarshalJSON(t *testing.T) {
+	var v Value
+	if err := json.Unmarshal([]byte(`{"b":1,"a":[{"d":true,"c":null}],"b":2}`), &v); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	want := Map{
+		{Key: "b", Value: float64(2)},
+		{Key: "a", Value: []interface{}{Map{{Key: "d", Value: true}, {Key: "c", Value: nil}}}},
+	}
+	if !reflect.DeepEqual(v.V, want) {
+		t.Errorf("Unmarshal() = %#v, want %#v", v.V, want)
+	}
+	got, err := json.Marshal(v.V)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if string(got) != `{"b":2,"a":[{"d":true,"c":null}]}` {
+		t.Errorf("Marshal() = %s", got)
+	}
+}
+
+func TestValue_UnmarshalYAML(t *testing.T) {
+	var v Value
+	if err := yaml.Unmarshal([]byte("- b: 1\n  a: {d: x, 1: z}\n- 3\n"), &v); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	want := []interface{}{
+		Map{
+			{Key: "b", Value: 1},
+			{Key: "a", Value: Map{{Key: "d", Value: "x"}, {Key: "1", Value: "z"}}},
+		},
+		3,
+	}
+	if !reflect.DeepEqual(v.V, want) {
+		t.Errorf("Unmarshal() = %#v, want %#v", v.V, want)
+	}
+	got, err := yaml.Marshal(v.V)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if string(got) != "- b: 1\n  a:\n    d: x\n    \"1\": z\n- 3\n" {
+		t.Errorf("Marshal() = %q", got)
+	}
+}
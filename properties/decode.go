@@ -0,0 +1,218 @@
+// Package properties
+// Copyright © 2021 zc2638 <zc2638@qq.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package properties
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/99nil/ditto/flat"
+	"github.com/99nil/ditto/ordered"
+)
+
+// Unmarshal decodes the properties of data into v, an *interface{} receiving maps
+// or an *ordered.Value receiving ordered.Maps in the order of the data.
+//
+// The syntax is that of java.util.Properties: '#' and '!' start comment lines,
+// a key ends at the first unescaped '=', ':' or space, a line ending with
+// an odd number of backslashes continues on the next one, and values may hold
+// the escapes \t, \n, \r, \f and \uXXXX. Keys are unflattened into nested maps
+// at dots, and indices such as list[0] make arrays. A repeated key replaces the previous one.
+func Unmarshal(data []byte, v interface{}) error {
+	return NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+// Decoder reads properties from an input stream, see Unmarshal.
+type Decoder struct {
+	r    io.Reader
+	done bool
+}
+
+// NewDecoder returns a new decoder that reads from r.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: r}
+}
+
+// Decode reads the properties of the input and stores them in v.
+// The input holds a single document, Decode returns io.EOF once it is read.
+func (dec *Decoder) Decode(v interface{}) error {
+	if dec.done {
+		return io.EOF
+	}
+	opts := flat.Options{Brackets: true}
+	switch v.(type) {
+	case *interface{}:
+	case *ordered.Value:
+		opts.Ordered = true
+	default:
+		return fmt.Errorf("cannot decode properties into %T", v)
+	}
+	data, err := ioutil.ReadAll(dec.r)
+	if err != nil {
+		return err
+	}
+	items, err := parse(data)
+	if err != nil {
+		return err
+	}
+	var value interface{} = map[string]interface{}{}
+	if opts.Ordered {
+		value = ordered.Map{}
+	}
+	if len(items) > 0 {
+		if value, err = flat.Unflatten(items, opts); err != nil {
+			return err
+		}
+	}
+	dec.done = true
+	switch val := v.(type) {
+	case *interface{}:
+		*val = value
+	case *ordered.Value:
+		val.V = value
+	}
+	return nil
+}
+
+// parse returns the properties of data in order.
+func parse(data []byte) (ordered.Map, error) {
+	data = bytes.TrimPrefix(data, []byte("\ufeff"))
+	var items ordered.Map
+	lines := strings.Split(strings.NewReplacer("\r\n", "\n", "\r", "\n").Replace(string(data)), "\n")
+	for n := 0; n < len(lines); n++ {
+		start := n + 1
+		line := strings.TrimLeft(lines[n], " \t\f")
+		if line == "" || line[0] == '#' || line[0] == '!' {
+			continue
+		}
+		// join the continuation lines, whose leading whitespace is dropped
+		for continues(line) && n+1 < len(lines) {
+			n++
+			line = line[:len(line)-1] + strings.TrimLeft(lines[n], " \t\f")
+		}
+		if continues(line) {
+			line = line[:len(line)-1]
+		}
+		key, value, err := split(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", start, err)
+		}
+		items.Set(key, value)
+	}
+	return items, nil
+}
+
+// continues reports whether line ends with an odd number of backslashes.
+func continues(line string) bool {
+	n := 0
+	for i := len(line) - 1; i >= 0 && line[i] == '\\'; i-- {
+		n++
+	}
+	return n%2 == 1
+}
+
+// split returns the unescaped key and value of a logical line.
+func split(line string) (string, string, error) {
+	end := 0
+	for end < len(line) {
+		c := line[end]
+		if c == '\\' {
+			end += 2
+			continue
+		}
+		if c == '=' || c == ':' || c == ' ' || c == '\t' || c == '\f' {
+			break
+		}
+		end++
+	}
+	if end > len(line) {
+		end = len(line)
+	}
+	rest := strings.TrimLeft(line[end:], " \t\f")
+	if rest != "" && (rest[0] == '=' || rest[0] == ':') {
+		rest = strings.TrimLeft(rest[1:], " \t\f")
+	}
+	key, err := unescape(line[:end])
+	if err != nil {
+		return "", "", err
+	}
+	value, err := unescape(rest)
+	if err != nil {
+		return "", "", err
+	}
+	return key, value, nil
+}
+
+// unescape replaces the escapes of s.
+func unescape(s string) (string, error) {
+	if !strings.Contains(s, "\\") {
+		return s, nil
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c != '\\' || i+1 == len(s) {
+			b.WriteByte(c)
+			continue
+		}
+		i++
+		switch c = s[i]; c {
+		case 't':
+			b.WriteByte('\t')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 'f':
+			b.WriteByte('\f')
+		case 'u':
+			r, err := unicode(s[i+1:])
+			if err != nil {
+				return "", err
+			}
+			i += 4
+			// a surrogate pair is written as two escapes
+			if utf16.IsSurrogate(r) && strings.HasPrefix(s[i+1:], "\\u") {
+				if low, err := unicode(s[i+3:]); err == nil {
+					if pair := utf16.DecodeRune(r, low); pair != '\uFFFD' {
+						r = pair
+						i += 6
+					}
+				}
+			}
+			b.WriteRune(r)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String(), nil
+}
+
+// unicode decodes the four hexadecimal digits starting s.
+func unicode(s string) (rune, error) {
+	if len(s) < 4 {
+		return 0, fmt.Errorf("malformed \\uXXXX escape %q", "\\u"+s)
+	}
+	n, err := strconv.ParseUint(s[:4], 16, 16)
+	if err != nil {
+		return 0, fmt.Errorf("malformed \\uXXXX escape %q", "\\u"+s[:4])
+	}
+	return rune(n), nil
+}
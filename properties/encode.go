@@ -0,0 +1,112 @@
+// Package properties
+// Copyright © 2021 zc2638 <zc2638@qq.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package properties
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/99nil/ditto/flat"
+	"github.com/99nil/ditto/ordered"
+)
+
+// Marshal returns the properties of v, a map or an ordered.Map,
+// whose nested values are flattened into dotted keys and indices such as list[0].
+// Characters outside of printable ASCII are written as \uXXXX escapes.
+func Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Encoder writes properties to an output stream, see Marshal.
+type Encoder struct {
+	w io.Writer
+}
+
+// NewEncoder returns a new encoder that writes to w.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: w}
+}
+
+// Encode writes the properties of v to the stream.
+func (enc *Encoder) Encode(v interface{}) error {
+	switch v.(type) {
+	case map[string]interface{}, ordered.Map:
+	default:
+		return fmt.Errorf("cannot encode %T as properties", v)
+	}
+	var buf bytes.Buffer
+	for _, item := range flat.Flatten(v, flat.Options{Brackets: true}) {
+		buf.WriteString(escape(item.Key, true))
+		buf.WriteByte('=')
+		buf.WriteString(escape(flat.Text(item.Value), false))
+		buf.WriteByte('\n')
+	}
+	_, err := enc.w.Write(buf.Bytes())
+	return err
+}
+
+// escape escapes s as a key or a value.
+func escape(s string, key bool) string {
+	var b strings.Builder
+	for i, r := range s {
+		switch r {
+		case '\\':
+			b.WriteString(`\\`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\f':
+			b.WriteString(`\f`)
+		case '=', ':':
+			if key {
+				b.WriteByte('\\')
+			}
+			b.WriteRune(r)
+		case ' ':
+			// the spaces of keys and the leading ones of values are separators
+			if key || i == 0 {
+				b.WriteByte('\\')
+			}
+			b.WriteRune(r)
+		case '#', '!':
+			// would start a comment line
+			if key && i == 0 {
+				b.WriteByte('\\')
+			}
+			b.WriteRune(r)
+		default:
+			if r >= ' ' && r < 0x7f {
+				b.WriteRune(r)
+				continue
+			}
+			if r1, r2 := utf16.EncodeRune(r); r1 != '\uFFFD' {
+				fmt.Fprintf(&b, `\u%04X\u%04X`, r1, r2)
+			} else {
+				fmt.Fprintf(&b, `\u%04X`, r)
+			}
+		}
+	}
+	return b.String()
+}
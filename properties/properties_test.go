@@ -0,0 +1,127 @@
+// Package properties
+// Copyright © 2021 zc2638 <zc2638@qq.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package properties
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/99nil/ditto/ordered"
+)
+
+func TestUnmarshal(t *testing.T) {
+	const data = `# Spring settings
+! also a comment
+server.port=8080
+server.address : 127.0.0.1
+spring.datasource.url = jdbc:h2:mem:test
+app.servers[0].name=a
+app.servers[1].name=b
+app.tags[0]=x
+app.tags[1]=y
+message = Hello, \
+          world\tand café 😀
+key\ with\ spaces\=x=value
+empty
+`
+	want := map[string]interface{}{
+		"server": map[string]interface{}{
+			"port":    "8080",
+			"address": "127.0.0.1",
+		},
+		"spring": map[string]interface{}{
+			"datasource": map[string]interface{}{"url": "jdbc:h2:mem:test"},
+		},
+		"app": map[string]interface{}{
+			"servers": []interface{}{
+				map[string]interface{}{"name": "a"},
+				map[string]interface{}{"name": "b"},
+			},
+			"tags": []interface{}{"x", "y"},
+		},
+		"message":           "Hello, world\tand café 😀",
+		"key with spaces=x": "value",
+		"empty":             "",
+	}
+	var v interface{}
+	if err := Unmarshal([]byte(data), &v); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if !reflect.DeepEqual(v, want) {
+		t.Errorf("Unmarshal() = %#v, want %#v", v, want)
+	}
+
+	var ov ordered.Value
+	if err := Unmarshal([]byte(data), &ov); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	wantKeys := []string{"server", "spring", "app", "message", "key with spaces=x", "empty"}
+	if keys := ov.V.(ordered.Map).Keys(); !reflect.DeepEqual(keys, wantKeys) {
+		t.Errorf("Unmarshal() keys = %v, want %v", keys, wantKeys)
+	}
+
+	if err := Unmarshal([]byte("a=\\u12"), &v); err == nil {
+		t.Error("Unmarshal() expected error for malformed escape")
+	}
+	if err := Unmarshal([]byte("a=1\na.b=2"), &v); err == nil {
+		t.Error("Unmarshal() expected error for conflicting keys")
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	v := ordered.Map{
+		{Key: "server", Value: map[string]interface{}{"port": float64(8080), "address": "127.0.0.1"}},
+		{Key: "app", Value: ordered.Map{
+			{Key: "servers", Value: []interface{}{map[string]interface{}{"name": "a"}}},
+			{Key: "enabled", Value: true},
+		}},
+		{Key: "key with=", Value: " café\n"},
+		{Key: "#hash", Value: "#1"},
+	}
+	want := `server.address=127.0.0.1
+server.port=8080
+app.servers[0].name=a
+app.enabled=true
+key\ with\==\ caf\u00E9\n
+\#hash=#1
+`
+	got, err := Marshal(v)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if string(got) != want {
+		t.Errorf("Marshal() = %s, want %s", got, want)
+	}
+
+	var back interface{}
+	if err := Unmarshal(got, &back); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if back.(map[string]interface{})["key with="] != " café\n" {
+		t.Errorf("Unmarshal() = %#v, want the escaped key and value back", back)
+	}
+
+	got, err = Marshal(map[string]interface{}{})
+	if err != nil || len(got) != 0 {
+		t.Errorf("Marshal() = %q, %v, want nothing for an empty map", got, err)
+	}
+	if err := Unmarshal(got, &back); err != nil || !reflect.DeepEqual(back, map[string]interface{}{}) {
+		t.Errorf("Unmarshal() = %#v, %v, want an empty map", back, err)
+	}
+
+	if _, err := Marshal([]interface{}{"a"}); err == nil {
+		t.Error("Marshal() expected error for an array")
+	}
+}
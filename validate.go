@@ -0,0 +1,267 @@
+// Package ditto
+// Copyright © 2021 zc2638 <zc2638@qq.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package ditto
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	dotenve "github.com/99nil/ditto/dotenv"
+	inie "github.com/99nil/ditto/ini"
+	jsone "github.com/99nil/ditto/json"
+	ndjsone "github.com/99nil/ditto/ndjson"
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v2"
+)
+
+// Diagnostic describes a problem found in a document.
+type Diagnostic struct {
+	// Line is the 1-based line of the problem.
+	Line int
+	// Column is the 1-based column of the problem,
+	// or 0 when the parser reports only a line.
+	Column int
+	// Offset is the byte offset of the problem, or of its line when Column is 0.
+	Offset int
+	// Message is a human-readable description of the problem.
+	Message string
+	// Snippet is the offending source line followed by a caret line.
+	Snippet string
+}
+
+func (d Diagnostic) Error() string {
+	if d.Line == 0 {
+		return d.Message
+	}
+	if d.Column == 0 {
+		return fmt.Sprintf("line %d: %s", d.Line, d.Message)
+	}
+	return fmt.Sprintf("%d:%d: %s", d.Line, d.Column, d.Message)
+}
+
+// ValidateFunc checks data and reports every problem found.
+type ValidateFunc func(data []byte) []Diagnostic
+
+// WithValidator sets the function checking the documents of the engine,
+// which reports every problem found rather than the first unmarshal error.
+// Registering the unmarshal function of the engine again drops it.
+func WithValidator(fn ValidateFunc) EngineOption {
+	return func(e *Engine) {
+		e.validate = fn
+	}
+}
+
+// Validate checks data in the named format with the default registry.
+// See Registry.Validate.
+func Validate(format string, data []byte) []Diagnostic {
+	return defaultRegistry.Validate(format, data)
+}
+
+// Validate checks data in the named format and returns its diagnostics,
+// which is empty when data is valid.
+// Engines without a validator are checked by their unmarshal function.
+func (r *Registry) Validate(format string, data []byte) []Diagnostic {
+	e, err := r.lookup(format, DirectionInput)
+	if err != nil {
+		return []Diagnostic{{Message: err.Error()}}
+	}
+	if e.validate != nil {
+		return e.validate(data)
+	}
+	var v interface{}
+	if err := e.Unmarshal(data, &v); err != nil {
+		return []Diagnostic{errorDiagnostic(data, err)}
+	}
+	return nil
+}
+
+var errorLineRe = regexp.MustCompile(`^line (\d+): `)
+
+// errorDiagnostic builds the diagnostic of an unmarshal error, on its line when the error tells it.
+func errorDiagnostic(data []byte, err error) Diagnostic {
+	var (
+		iniErr *inie.SyntaxError
+		envErr *dotenve.SyntaxError
+	)
+	switch {
+	case errors.As(err, &iniErr):
+		msg := iniErr.Msg
+		if iniErr.Err != nil {
+			msg += ": " + iniErr.Err.Error()
+		}
+		return newDiagnostic(data, iniErr.Line, 0, msg)
+	case errors.As(err, &envErr):
+		return newDiagnostic(data, envErr.Line, 0, envErr.Msg)
+	}
+	msg := err.Error()
+	if m := errorLineRe.FindStringSubmatch(msg); m != nil {
+		line, _ := strconv.Atoi(m[1])
+		return newDiagnostic(data, line, 0, msg[len(m[0]):])
+	}
+	return Diagnostic{Message: msg}
+}
+
+// newDiagnostic builds a diagnostic for the 1-based line and column of data.
+func newDiagnostic(data []byte, line, column int, message string) Diagnostic {
+	d := Diagnostic{Line: line, Column: column, Message: message}
+	start := 0
+	for l := 1; l < line; l++ {
+		i := bytes.IndexByte(data[start:], '\n')
+		if i < 0 {
+			break
+		}
+		start += i + 1
+	}
+	end := len(data)
+	if i := bytes.IndexByte(data[start:], '\n'); i >= 0 {
+		end = start + i
+	}
+	src := strings.TrimSuffix(string(data[start:end]), "\r")
+	d.Offset = start
+	if column == 0 {
+		d.Snippet = src
+		return d
+	}
+	d.Offset += column - 1
+	if d.Offset > len(data) {
+		d.Offset = len(data)
+	}
+	caret := make([]byte, 0, column)
+	for i := 0; i < column-1 && i < len(src); i++ {
+		// keep tabs so the caret lines up with the source
+		if src[i] == '\t' {
+			caret = append(caret, '\t')
+		} else {
+			caret = append(caret, ' ')
+		}
+	}
+	caret = append(caret, '^')
+	d.Snippet = src + "\n" + string(caret)
+	return d
+}
+
+// newDiagnosticAt builds a diagnostic for the byte offset of data.
+func newDiagnosticAt(data []byte, offset int, message string) Diagnostic {
+	if offset > len(data) {
+		offset = len(data)
+	}
+	line := 1 + bytes.Count(data[:offset], []byte("\n"))
+	column := offset - bytes.LastIndexByte(data[:offset], '\n')
+	return newDiagnostic(data, line, column, message)
+}
+
+// maxJSONDiagnostics bounds the errors reported for a single JSON document.
+const maxJSONDiagnostics = 100
+
+func validateJSON(mode jsone.Mode) ValidateFunc {
+	return func(data []byte) []Diagnostic {
+		var diagnostics []Diagnostic
+		for _, err := range jsone.ValidateAllMode(data, maxJSONDiagnostics, mode) {
+			diagnostics = append(diagnostics, newDiagnostic(data, err.Line, err.Column, err.Msg+" at "+err.Path))
+		}
+		return diagnostics
+	}
+}
+
+func validateNDJSON(data []byte) []Diagnostic {
+	var diagnostics []Diagnostic
+	for _, err := range ndjsone.ValidateAll(data, maxJSONDiagnostics) {
+		diagnostics = append(diagnostics, newDiagnostic(data, err.Line, err.Column, err.Msg+" at "+err.Path))
+	}
+	return diagnostics
+}
+
+var yamlLineRe = regexp.MustCompile(`line (\d+): `)
+
+func validateYaml(data []byte) []Diagnostic {
+	d := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var v interface{}
+		err := d.Decode(&v)
+		if err == io.EOF {
+			return nil
+		}
+		if err == nil {
+			continue
+		}
+		var diagnostics []Diagnostic
+		var typeErr *yaml.TypeError
+		messages := []string{err.Error()}
+		if errors.As(err, &typeErr) {
+			messages = typeErr.Errors
+		}
+		for _, msg := range messages {
+			msg = strings.TrimPrefix(msg, "yaml: ")
+			line := 0
+			if m := yamlLineRe.FindStringSubmatchIndex(msg); m != nil {
+				line, _ = strconv.Atoi(msg[m[2]:m[3]])
+				msg = msg[:m[0]] + msg[m[1]:]
+			}
+			if line == 0 {
+				diagnostics = append(diagnostics, Diagnostic{Message: msg})
+				continue
+			}
+			diagnostics = append(diagnostics, newDiagnostic(data, line, 0, msg))
+		}
+		// the decoder cannot resume after an error
+		return diagnostics
+	}
+}
+
+func validateTOML(data []byte) []Diagnostic {
+	var v map[string]interface{}
+	err := toml.Unmarshal(data, &v)
+	if err == nil {
+		return nil
+	}
+	var decodeErr *toml.DecodeError
+	if !errors.As(err, &decodeErr) {
+		return []Diagnostic{{Message: err.Error()}}
+	}
+	line, column := decodeErr.Position()
+	return []Diagnostic{newDiagnostic(data, line, column, strings.TrimPrefix(err.Error(), "toml: "))}
+}
+
+func validateXML(data []byte) []Diagnostic {
+	d := xml.NewDecoder(bytes.NewReader(data))
+	hasRoot := false
+	for {
+		token, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			msg := err.Error()
+			var syntaxErr *xml.SyntaxError
+			if errors.As(err, &syntaxErr) {
+				msg = syntaxErr.Msg
+			}
+			return []Diagnostic{newDiagnosticAt(data, int(d.InputOffset()), msg)}
+		}
+		if _, ok := token.(xml.StartElement); ok {
+			hasRoot = true
+		}
+	}
+	if !hasRoot {
+		return []Diagnostic{newDiagnosticAt(data, len(data), "missing root element")}
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+// Package ditto
+// Copyright © 2021 zc2638 <zc2638@qq.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package ditto
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	jsone "github.com/99nil/ditto/json"
+)
+
+func TestValidate(t *testing.T) {
+	type args struct {
+		format string
+		data   string
+	}
+	tests := []struct {
+		name string
+		args args
+		want []Diagnostic
+	}{
+		{
+			name: "json-valid",
+			args: args{format: FormatJSON, data: jsonStr},
+		},
+		{
+			name: "json",
+			args: args{format: FormatJSON, data: "{\n  \"a\" 1\n}"},
+			want: []Diagnostic{{Line: 2, Column: 7, Offset: 8, Message: "expected ':' after object key at $.a", Snippet: "  \"a\" 1\n      ^"}},
+		},
+		{
+			name: "json-multiple",
+			args: args{format: FormatJSON, data: "[1 2,\n tru]"},
+			want: []Diagnostic{
+				{Line: 1, Column: 4, Offset: 3, Message: "expected ',' or ']' after array element at $", Snippet: "[1 2,\n   ^"},
+				{Line: 2, Column: 5, Offset: 10, Message: "invalid literal, expected 'true' at $[2]", Snippet: " tru]\n    ^"},
+			},
+		},
+		{
+			name: "yaml",
+			args: args{format: FormatYaml, data: "a: 1\n  b: 2\n"},
+			want: []Diagnostic{{Line: 2, Offset: 5, Message: "mapping values are not allowed in this context", Snippet: "  b: 2"}},
+		},
+		{
+			name: "toml",
+			args: args{format: FormatTOML, data: "a = 1\nb = = 2\n"},
+			want: []Diagnostic{{Line: 2, Column: 5, Offset: 10, Message: "incomplete number", Snippet: "b = = 2\n    ^"}},
+		},
+		{
+			name: "xml",
+			args: args{format: FormatXML, data: "<a>\n\t<b></c>\n</a>"},
+			want: []Diagnostic{{Line: 2, Column: 9, Offset: 12, Message: "element <b> closed by </c>", Snippet: "\t<b></c>\n\t       ^"}},
+		},
+		{
+			name: "ini",
+			args: args{format: FormatINI, data: "a = 1\n[a.b]\n"},
+			want: []Diagnostic{{Line: 2, Offset: 6, Message: "section a.b: conflicting key", Snippet: "[a.b]"}},
+		},
+		{
+			name: "dotenv",
+			args: args{format: FormatDotenv, data: "A=1\n=2\n"},
+			want: []Diagnostic{{Line: 2, Offset: 4, Message: `invalid variable name ""`, Snippet: "=2"}},
+		},
+		{
+			name: "properties",
+			args: args{format: FormatProperties, data: "a=1\nb=\\u12\n"},
+			want: []Diagnostic{{Line: 2, Offset: 4, Message: `malformed \uXXXX escape "\\u12"`, Snippet: `b=\u12`}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Validate(tt.args.format, []byte(tt.args.data)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Validate() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegistry_Validate(t *testing.T) {
+	r := NewRegistry()
+	if got := r.Validate(FormatJSON, []byte("{")); len(got) != 1 || got[0].Line != 0 {
+		t.Errorf("Validate() = %#v, want an unknown engine diagnostic", got)
+	}
+
+	// a JSON engine accepting anything replaces the JSON validator
+	r.Register(FormatJSON, json.Marshal, func([]byte, interface{}) error { return nil })
+	if got := r.Validate(FormatJSON, []byte("{")); got != nil {
+		t.Errorf("Validate() = %#v, want no diagnostics", got)
+	}
+	r.Register(FormatJSON, json.Marshal, json.Unmarshal, WithValidator(validateJSON(jsone.Strict)))
+	if got := r.Validate(FormatJSON, []byte("{")); len(got) != 1 || got[0].Line != 1 {
+		t.Errorf("Validate() = %#v, want a diagnostic on line 1", got)
+	}
+}
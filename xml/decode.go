@@ -0,0 +1,65 @@
+// Package xml
+// Copyright © 2021 zc2638 <zc2638@qq.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package xml
+
+import (
+	xmle "encoding/xml"
+	"io"
+	"io/ioutil"
+)
+
+// Unmarshal parses the XML-encoded data into v like encoding/xml.Unmarshal,
+// giving a Document the source it needs to keep CDATA sections and markup before the root element.
+func Unmarshal(data []byte, v interface{}) error {
+	if doc, ok := v.(*Document); ok && doc != nil {
+		doc.src = data
+		defer func() { doc.src = nil }()
+	}
+	return xmle.Unmarshal(data, v)
+}
+
+// Decoder reads XML values from an input stream, see Unmarshal.
+type Decoder struct {
+	r io.Reader
+	d *xmle.Decoder
+}
+
+// NewDecoder returns a new decoder that reads from r.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: r}
+}
+
+// Decode reads the next XML value from its input and stores it in v.
+// A Document keeping markup is decoded from the rest of the input, read at once.
+func (dec *Decoder) Decode(v interface{}) error {
+	if opts := options(v); dec.d == nil && opts != nil && opts.Markup {
+		data, err := ioutil.ReadAll(dec.r)
+		if err != nil {
+			return err
+		}
+		dec.r = eofReader{}
+		return Unmarshal(data, v)
+	}
+	if dec.d == nil {
+		dec.d = xmle.NewDecoder(dec.r)
+	}
+	return dec.d.Decode(v)
+}
+
+type eofReader struct{}
+
+func (eofReader) Read([]byte) (int, error) {
+	return 0, io.EOF
+}
@@ -0,0 +1,143 @@
+// Package xml
+// Copyright © 2021 zc2638 <zc2638@qq.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package xml
+
+import (
+	"bytes"
+	xmle "encoding/xml"
+	"io"
+)
+
+// Header is the XML declaration written before the root element of a Document
+// when its options ask for it.
+const Header = `<?xml version="1.0" encoding="UTF-8"?>`
+
+// Marshal returns the XML encoding of v like encoding/xml.Marshal,
+// applying the Declaration and SelfClosing options of a Document.
+func Marshal(v interface{}) ([]byte, error) {
+	data, err := xmle.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	opts := options(v)
+	if opts == nil {
+		return data, nil
+	}
+	if opts.SelfClosing {
+		data = selfClose(data)
+	}
+	if opts.Declaration {
+		header := Header
+		if !opts.Compact {
+			header += "\n"
+		}
+		data = append([]byte(header), data...)
+	}
+	return data, nil
+}
+
+// Encoder writes XML values to an output stream, see Marshal.
+type Encoder struct {
+	w io.Writer
+}
+
+// NewEncoder returns a new encoder that writes to w.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: w}
+}
+
+// Encode writes the XML encoding of v to the stream.
+func (enc *Encoder) Encode(v interface{}) error {
+	data, err := Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = enc.w.Write(data)
+	return err
+}
+
+// options returns the options of v when it is a Document.
+func options(v interface{}) *Options {
+	switch doc := v.(type) {
+	case Document:
+		return &doc.Options
+	case *Document:
+		if doc != nil {
+			return &doc.Options
+		}
+	}
+	return nil
+}
+
+// selfClose rewrites the elements of data having no content, such as <a></a>, as <a/>.
+// The encoder escapes '<' and '>' in character data and attribute values,
+// so only comments, CDATA sections and processing instructions need to be skipped.
+func selfClose(data []byte) []byte {
+	out := make([]byte, 0, len(data))
+	for i := 0; i < len(data); {
+		if data[i] != '<' {
+			out = append(out, data[i])
+			i++
+			continue
+		}
+		rest := data[i:]
+		if end := skipMarkup(rest); end > 0 {
+			out = append(out, rest[:end]...)
+			i += end
+			continue
+		}
+		end := bytes.IndexByte(rest, '>')
+		if end < 0 || rest[1] == '/' {
+			out = append(out, data[i])
+			i++
+			continue
+		}
+		name := rest[1:end]
+		if n := bytes.IndexAny(name, " \t\r\n"); n >= 0 {
+			name = name[:n]
+		}
+		closing := append(append([]byte("</"), name...), '>')
+		if !bytes.HasPrefix(rest[end+1:], closing) {
+			out = append(out, rest[:end+1]...)
+			i += end + 1
+			continue
+		}
+		out = append(out, rest[:end]...)
+		out = append(out, '/', '>')
+		i += end + 1 + len(closing)
+	}
+	return out
+}
+
+// skipMarkup returns the length of the comment, CDATA section or processing instruction
+// data starts with, 0 if none.
+func skipMarkup(data []byte) int {
+	for _, delims := range [][2]string{
+		{"<!--", "-->"},
+		{"<![CDATA[", "]]>"},
+		{"<?", "?>"},
+		{"<!", ">"},
+	} {
+		if !bytes.HasPrefix(data, []byte(delims[0])) {
+			continue
+		}
+		end := bytes.Index(data[len(delims[0]):], []byte(delims[1]))
+		if end < 0 {
+			return len(data)
+		}
+		return len(delims[0]) + end + len(delims[1])
+	}
+	return 0
+}
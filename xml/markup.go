@@ -0,0 +1,338 @@
+// Package xml
+// Copyright © 2021 zc2638 <zc2638@qq.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package xml
+
+import (
+	"bytes"
+	xmle "encoding/xml"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/99nil/ditto/ordered"
+)
+
+var (
+	cdataStart = []byte("<![CDATA[")
+
+	nameType      = reflect.TypeOf(xmle.Name{})
+	attrsType     = reflect.TypeOf([]xmle.Attr{})
+	stringType    = reflect.TypeOf("")
+	marshalerType = reflect.TypeOf((*xmle.Marshaler)(nil)).Elem()
+)
+
+type cdataData struct {
+	XMLName xmle.Name
+	Attr    []xmle.Attr `xml:",attr"`
+	Value   string      `xml:",cdata"`
+}
+
+type innerData struct {
+	XMLName xmle.Name
+	Attr    []xmle.Attr `xml:",attr"`
+	Value   string      `xml:",innerxml"`
+}
+
+// hasMarkupChars reports whether s would be escaped as character data.
+func hasMarkupChars(s string) bool {
+	return strings.ContainsAny(s, "<>&")
+}
+
+// tokens encodes a part of the content of an element through the encoder of the element.
+type tokens func(e *xmle.Encoder) error
+
+func (f tokens) MarshalXML(e *xmle.Encoder, _ xmle.StartElement) error {
+	return f(e)
+}
+
+// content encodes the items of a map holding markup as the element start, in order.
+// The encoder writes CDATA sections only for struct fields,
+// so the element is encoded as a struct with a field for every part of its content.
+func (enc *encoder) content(start xmle.StartElement, items ordered.Map) error {
+	start = enc.declare(start)
+	defer enc.undeclare()
+
+	fields := []reflect.StructField{
+		{Name: "XMLName", Type: nameType},
+		{Name: "Attr", Type: attrsType, Tag: `xml:",attr"`},
+	}
+	values := []interface{}{start.Name, start.Attr}
+	add := func(tag reflect.StructTag, v interface{}) {
+		typ := marshalerType
+		if _, ok := v.(string); ok {
+			typ = stringType
+		}
+		fields = append(fields, reflect.StructField{Name: "F" + strconv.Itoa(len(fields)), Type: typ, Tag: tag})
+		values = append(values, v)
+	}
+	for _, item := range items {
+		key, value := item.Key, item.Value
+		switch key {
+		case CDataKey:
+			for _, s := range texts(value) {
+				add(`xml:",cdata"`, s)
+			}
+		case CommentKey:
+			for _, s := range texts(value) {
+				add(`xml:",comment"`, s)
+			}
+		case ProcInstKey:
+			for _, s := range texts(value) {
+				pi := procInst(s)
+				add(`xml:",any"`, tokens(func(e *xmle.Encoder) error { return e.EncodeToken(pi) }))
+			}
+		case enc.opts.textKey():
+			text := xmle.CharData(textOf(value))
+			add(`xml:",any"`, tokens(func(e *xmle.Encoder) error { return e.EncodeToken(text) }))
+		default:
+			add(`xml:",any"`, tokens(func(*xmle.Encoder) error { return enc.field(key, value) }))
+		}
+	}
+
+	v := reflect.New(reflect.StructOf(fields)).Elem()
+	for i, value := range values {
+		v.Field(i).Set(reflect.ValueOf(value))
+	}
+	return enc.e.Encode(v.Interface())
+}
+
+// mixed encodes the ContentKey list of an element with mixed content as the element start,
+// followed by its other child elements. The content is encoded without indentation,
+// which would change the text, and written as is inside the element.
+func (enc *encoder) mixed(start xmle.StartElement, content interface{}, fields ordered.Map) error {
+	start = enc.declare(start)
+	defer enc.undeclare()
+
+	var buf bytes.Buffer
+	inner := &encoder{e: xmle.NewEncoder(&buf), opts: enc.opts, scopes: enc.scopes, prefixes: enc.prefixes}
+	nodes, ok := content.([]interface{})
+	if !ok {
+		nodes = []interface{}{content}
+	}
+	for _, n := range nodes {
+		if err := inner.node(&buf, n); err != nil {
+			return err
+		}
+	}
+	for _, item := range fields {
+		if err := inner.field(item.Key, item.Value); err != nil {
+			return err
+		}
+	}
+	if err := inner.e.Flush(); err != nil {
+		return err
+	}
+	enc.prefixes = inner.prefixes
+	return enc.e.Encode(innerData{
+		XMLName: start.Name,
+		Attr:    start.Attr,
+		Value:   buf.String(),
+	})
+}
+
+// node encodes a node of mixed content, text or a map of child elements and markup, to buf.
+func (enc *encoder) node(buf *bytes.Buffer, n interface{}) error {
+	var items ordered.Map
+	switch val := n.(type) {
+	case map[string]interface{}:
+		for _, key := range sortXML(val) {
+			items = append(items, ordered.Item{Key: key, Value: val[key]})
+		}
+	case ordered.Map:
+		items = val
+	default:
+		return enc.e.EncodeToken(xmle.CharData(textOf(val)))
+	}
+	for _, item := range items {
+		var parts []xmle.Token
+		switch {
+		case item.Key == CDataKey && enc.opts.Markup:
+			// CDATA sections cannot be encoded as tokens
+			if err := enc.e.Flush(); err != nil {
+				return err
+			}
+			for _, s := range texts(item.Value) {
+				buf.WriteString(cdata(s))
+			}
+		case item.Key == CommentKey && enc.opts.Markup:
+			for _, s := range texts(item.Value) {
+				parts = append(parts, xmle.Comment(s))
+			}
+		case item.Key == ProcInstKey && enc.opts.Markup:
+			for _, s := range texts(item.Value) {
+				parts = append(parts, procInst(s))
+			}
+		case item.Key == enc.opts.textKey():
+			parts = append(parts, xmle.CharData(textOf(item.Value)))
+		default:
+			if err := enc.field(item.Key, item.Value); err != nil {
+				return err
+			}
+		}
+		for _, token := range parts {
+			if err := enc.e.EncodeToken(token); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// cdata returns s as a CDATA section, split where s holds its end.
+func cdata(s string) string {
+	return string(cdataStart) + strings.ReplaceAll(s, "]]>", "]]]]><![CDATA[>") + "]]>"
+}
+
+// procInst returns the processing instruction held by s, the target followed by the instruction.
+func procInst(s string) xmle.ProcInst {
+	target, inst := s, ""
+	if i := strings.IndexAny(s, " \t\r\n"); i >= 0 {
+		target, inst = s[:i], strings.TrimLeft(s[i:], " \t\r\n")
+	}
+	return xmle.ProcInst{Target: target, Inst: []byte(inst)}
+}
+
+// texts returns the strings held by a markup key.
+func texts(v interface{}) []string {
+	arr, ok := v.([]interface{})
+	if !ok {
+		return []string{textOf(v)}
+	}
+	s := make([]string, 0, len(arr))
+	for _, elem := range arr {
+		s = append(s, textOf(elem))
+	}
+	return s
+}
+
+// isCDATA reports whether the token read from src between offsets start and end is a CDATA section.
+func isCDATA(src []byte, start, end int64) bool {
+	if src == nil || start < 0 || end > int64(len(src)) || start > end {
+		return false
+	}
+	return bytes.HasPrefix(src[start:end], cdataStart)
+}
+
+// markup returns the key and value of a comment or processing instruction token.
+func markup(token xmle.Token) (string, string, bool) {
+	switch tv := token.(type) {
+	case xmle.Comment:
+		return CommentKey, string(tv), true
+	case xmle.ProcInst:
+		if tv.Target == "xml" {
+			return "", "", false
+		}
+		if len(tv.Inst) == 0 {
+			return ProcInstKey, tv.Target, true
+		}
+		return ProcInstKey, tv.Target + " " + string(tv.Inst), true
+	}
+	return "", "", false
+}
+
+// splitProlog returns the root element data without its PrologKey, and the value of the key.
+func splitProlog(data interface{}) (interface{}, interface{}) {
+	switch val := data.(type) {
+	case map[string]interface{}:
+		nodes, ok := val[PrologKey]
+		if !ok {
+			return data, nil
+		}
+		m := make(map[string]interface{}, len(val)-1)
+		for k, v := range val {
+			if k != PrologKey {
+				m[k] = v
+			}
+		}
+		return m, nodes
+	case ordered.Map:
+		var nodes interface{}
+		m := make(ordered.Map, 0, len(val))
+		for _, item := range val {
+			if item.Key == PrologKey {
+				nodes = item.Value
+				continue
+			}
+			m = append(m, item)
+		}
+		return m, nodes
+	}
+	return data, nil
+}
+
+// prolog encodes the comments and processing instructions of a PrologKey before the root element.
+func (enc *encoder) prolog(nodes interface{}) error {
+	if nodes == nil {
+		return nil
+	}
+	arr, ok := nodes.([]interface{})
+	if !ok {
+		arr = []interface{}{nodes}
+	}
+	var parts []xmle.Token
+	for _, n := range arr {
+		var items ordered.Map
+		switch val := n.(type) {
+		case map[string]interface{}:
+			for _, key := range sortXML(val) {
+				items = append(items, ordered.Item{Key: key, Value: val[key]})
+			}
+		case ordered.Map:
+			items = val
+		default:
+			items = ordered.Map{{Key: CommentKey, Value: val}}
+		}
+		for _, item := range items {
+			for _, s := range texts(item.Value) {
+				switch item.Key {
+				case CommentKey:
+					parts = append(parts, xmle.Comment(s))
+				case ProcInstKey:
+					parts = append(parts, procInst(s))
+				}
+			}
+		}
+	}
+	for _, token := range parts {
+		if err := enc.e.EncodeToken(token); err != nil {
+			return err
+		}
+		// the encoder does not indent the tokens, put each one on its own line
+		if !enc.opts.Compact {
+			if err := enc.e.EncodeToken(xmle.CharData("\n")); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// prolog returns the comments and processing instructions of src before the root element.
+func prolog(src []byte) []xmle.Token {
+	var nodes []xmle.Token
+	d := xmle.NewDecoder(bytes.NewReader(src))
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return nodes
+		}
+		switch token.(type) {
+		case xmle.StartElement:
+			return nodes
+		case xmle.Comment, xmle.ProcInst:
+			nodes = append(nodes, xmle.CopyToken(token))
+		}
+	}
+}
@@ -0,0 +1,239 @@
+// Package xml
+// Copyright © 2021 zc2638 <zc2638@qq.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package xml
+
+import (
+	xmle "encoding/xml"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// NamespaceMode selects how the names of namespaced elements and attributes are written as keys.
+type NamespaceMode int
+
+const (
+	// NamespaceNone keeps only the local names.
+	NamespaceNone NamespaceMode = iota
+	// NamespacePrefix keeps the names as written, such as "soap:Envelope",
+	// namespace declarations being attributes like any other.
+	// Without AttrPrefix, the declarations are kept as keys of the root element,
+	// such as "xmlns:soap", and encoded back as attributes.
+	NamespacePrefix
+	// NamespaceURI qualifies the names with their namespace URI,
+	// such as "{http://schemas.xmlsoap.org/soap/envelope/}Envelope".
+	// Namespace declarations are dropped when decoding and generated when encoding.
+	NamespaceURI
+)
+
+// xmlURL is the namespace bound to the xml prefix.
+const xmlURL = "http://www.w3.org/XML/1998/namespace"
+
+const xmlnsPrefix = "xmlns"
+
+// key returns the key of an element or attribute name.
+func (o *Options) key(name xmle.Name, ns namespaces) string {
+	switch {
+	case name.Space == "":
+		return name.Local
+	case name.Space == xmlnsPrefix:
+		return xmlnsPrefix + ":" + name.Local
+	case o.Namespaces == NamespaceURI:
+		return "{" + name.Space + "}" + name.Local
+	case o.Namespaces == NamespacePrefix:
+		prefix, ok := ns.prefix(name.Space)
+		if !ok {
+			// the prefix was not declared and is left as is by the decoder
+			prefix = name.Space
+		}
+		if prefix == "" {
+			return name.Local
+		}
+		return prefix + ":" + name.Local
+	}
+	return name.Local
+}
+
+// isDeclaration reports whether attr declares a namespace.
+func isDeclaration(attr xmle.Attr) bool {
+	return attr.Name.Space == xmlnsPrefix || (attr.Name.Space == "" && attr.Name.Local == xmlnsPrefix)
+}
+
+// isDeclarationKey reports whether key holds a namespace declaration kept without AttrPrefix.
+func (o *Options) isDeclarationKey(key string) bool {
+	return o.AttrPrefix == "" && o.Namespaces == NamespacePrefix &&
+		(key == xmlnsPrefix || strings.HasPrefix(key, xmlnsPrefix+":"))
+}
+
+// namespaces holds the prefixes bound to namespace URIs by the elements being decoded.
+type namespaces []map[string]string
+
+func (ns *namespaces) push(attrs []xmle.Attr) {
+	var scope map[string]string
+	for _, attr := range attrs {
+		if !isDeclaration(attr) {
+			continue
+		}
+		if scope == nil {
+			scope = make(map[string]string)
+		}
+		if attr.Name.Space == xmlnsPrefix {
+			scope[attr.Value] = attr.Name.Local
+		} else {
+			scope[attr.Value] = ""
+		}
+	}
+	*ns = append(*ns, scope)
+}
+
+func (ns *namespaces) pop() {
+	*ns = (*ns)[:len(*ns)-1]
+}
+
+// prefix returns the innermost prefix bound to uri, empty for the default namespace.
+func (ns namespaces) prefix(uri string) (string, bool) {
+	if uri == xmlURL {
+		return "xml", true
+	}
+	for i := len(ns) - 1; i >= 0; i-- {
+		if prefix, ok := ns[i][uri]; ok {
+			return prefix, true
+		}
+	}
+	return "", false
+}
+
+// splitURI splits a key written as {uri}local.
+func splitURI(key string) (uri, local string, ok bool) {
+	if !strings.HasPrefix(key, "{") {
+		return "", key, false
+	}
+	i := strings.LastIndex(key, "}")
+	if i < 0 {
+		return "", key, false
+	}
+	return key[1:i], key[i+1:], true
+}
+
+// declare adds the namespace declarations of Prefixes to the root element start,
+// and in NamespaceURI mode writes its names with prefixes, declaring the missing ones.
+// Every call must be followed by a call to undeclare once the element is encoded.
+func (enc *encoder) declare(start xmle.StartElement) xmle.StartElement {
+	var decls []xmle.Attr
+	if len(enc.scopes) == 0 {
+		prefixes := make([]string, 0, len(enc.opts.Prefixes))
+		for prefix := range enc.opts.Prefixes {
+			prefixes = append(prefixes, prefix)
+		}
+		sort.Strings(prefixes)
+		for _, prefix := range prefixes {
+			decl := declaration(prefix, enc.opts.Prefixes[prefix])
+			if !hasAttr(start.Attr, decl.Name.Local) {
+				decls = append(decls, decl)
+			}
+		}
+	}
+	if enc.opts.Namespaces == NamespaceURI {
+		attrs := make([]xmle.Attr, 0, len(start.Attr))
+		start.Name.Local = enc.qualify(start.Name.Local, true, &decls)
+		for _, attr := range start.Attr {
+			attr.Name.Local = enc.qualify(attr.Name.Local, false, &decls)
+			attrs = append(attrs, attr)
+		}
+		start.Attr = attrs
+	}
+	enc.scopes = append(enc.scopes, decls)
+	start.Attr = append(decls[:len(decls):len(decls)], start.Attr...)
+	return start
+}
+
+func (enc *encoder) undeclare() {
+	enc.scopes = enc.scopes[:len(enc.scopes)-1]
+}
+
+func declaration(prefix, uri string) xmle.Attr {
+	name := xmlnsPrefix
+	if prefix != "" {
+		name += ":" + prefix
+	}
+	return xmle.Attr{Name: xmle.Name{Local: name}, Value: uri}
+}
+
+// qualify returns the name written with a prefix for a key written as {uri}local,
+// adding the declaration of a new prefix to decls when uri is not bound.
+func (enc *encoder) qualify(key string, element bool, decls *[]xmle.Attr) string {
+	uri, local, ok := splitURI(key)
+	if !ok {
+		if element && enc.lookup(*decls, xmlnsPrefix) != "" {
+			// leave the default namespace of the parent
+			*decls = append(*decls, declaration("", ""))
+		}
+		return key
+	}
+	if uri == xmlURL {
+		return "xml:" + local
+	}
+	if element && enc.lookup(*decls, xmlnsPrefix) == uri {
+		return local
+	}
+	prefix, ok := enc.bound(*decls, uri)
+	if !ok {
+		prefix = enc.newPrefix(*decls)
+		*decls = append(*decls, declaration(prefix, uri))
+	}
+	return prefix + ":" + local
+}
+
+// lookup returns the namespace declared by the attribute name in scope.
+func (enc *encoder) lookup(decls []xmle.Attr, name string) string {
+	for _, decl := range decls {
+		if decl.Name.Local == name {
+			return decl.Value
+		}
+	}
+	for i := len(enc.scopes) - 1; i >= 0; i-- {
+		for _, decl := range enc.scopes[i] {
+			if decl.Name.Local == name {
+				return decl.Value
+			}
+		}
+	}
+	return ""
+}
+
+// bound returns a prefix bound to uri in scope.
+func (enc *encoder) bound(decls []xmle.Attr, uri string) (string, bool) {
+	scopes := append(enc.scopes[:len(enc.scopes):len(enc.scopes)], decls)
+	for i := len(scopes) - 1; i >= 0; i-- {
+		for _, decl := range scopes[i] {
+			prefix := strings.TrimPrefix(decl.Name.Local, xmlnsPrefix+":")
+			if decl.Value == uri && prefix != decl.Name.Local && enc.lookup(decls, decl.Name.Local) == uri {
+				return prefix, true
+			}
+		}
+	}
+	return "", false
+}
+
+// newPrefix generates a prefix not bound in scope.
+func (enc *encoder) newPrefix(decls []xmle.Attr) string {
+	for {
+		enc.prefixes++
+		prefix := "ns" + strconv.Itoa(enc.prefixes)
+		if _, ok := enc.opts.Prefixes[prefix]; !ok && enc.lookup(decls, xmlnsPrefix+":"+prefix) == "" {
+			return prefix
+		}
+	}
+}
@@ -0,0 +1,95 @@
+// Package xml
+// Copyright © 2021 zc2638 <zc2638@qq.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package xml
+
+import (
+	xmle "encoding/xml"
+	"io"
+	"strings"
+)
+
+// RecordDecoder reads the elements found at a path of a document one at a time,
+// such as every book of /catalog/book, without holding the whole document in memory.
+type RecordDecoder struct {
+	d    *xmle.Decoder
+	opts Options
+	path []string
+	// names of the elements enclosing the current token
+	stack []string
+}
+
+// NewRecordDecoder returns a decoder of the elements of r at path,
+// written with the local names of the elements from the root, such as /catalog/book.
+func NewRecordDecoder(r io.Reader, path string, opts Options) *RecordDecoder {
+	return &RecordDecoder{
+		d:    xmle.NewDecoder(r),
+		opts: opts,
+		path: strings.Split(strings.Trim(path, "/"), "/"),
+	}
+}
+
+// Next returns the content of the next record decoded with the options of the decoder,
+// or io.EOF when there are no more records.
+func (rd *RecordDecoder) Next() (map[string]interface{}, error) {
+	doc := &Document{Options: rd.opts}
+	if err := rd.Decode(doc); err != nil {
+		return nil, err
+	}
+	return doc.Value.(map[string]interface{}), nil
+}
+
+// Decode stores the next record in v, like the Decode method of encoding/xml.Decoder.
+// A Document is decoded with its own options, and other values with encoding/xml.
+// It returns io.EOF when there are no more records.
+func (rd *RecordDecoder) Decode(v interface{}) error {
+	start, err := rd.next()
+	if err != nil {
+		return err
+	}
+	return rd.d.DecodeElement(v, start)
+}
+
+// next returns the start of the next record.
+func (rd *RecordDecoder) next() (*xmle.StartElement, error) {
+	for {
+		token, err := rd.d.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch tv := token.(type) {
+		case xmle.StartElement:
+			rd.stack = append(rd.stack, tv.Name.Local)
+			if rd.matches() {
+				// the end of the record is read by DecodeElement
+				rd.stack = rd.stack[:len(rd.stack)-1]
+				return &tv, nil
+			}
+		case xmle.EndElement:
+			rd.stack = rd.stack[:len(rd.stack)-1]
+		}
+	}
+}
+
+func (rd *RecordDecoder) matches() bool {
+	if len(rd.stack) != len(rd.path) {
+		return false
+	}
+	for i, name := range rd.path {
+		if rd.stack[i] != name {
+			return false
+		}
+	}
+	return true
+}
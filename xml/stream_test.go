@@ -0,0 +1,90 @@
+// Package xml
+// Copyright © 2021 zc2638 <zc2638@qq.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package xml
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/99nil/ditto/ordered"
+)
+
+const catalog = `<?xml version="1.0"?>
+<catalog>
+    <book id="1"><title>Go</title><price>10</price></book>
+    <shelf><book><title>ignored</title></book></shelf>
+    <book id="2"><title>XML</title><price>12.5</price></book>
+</catalog>`
+
+func TestRecordDecoder_Next(t *testing.T) {
+	rd := NewRecordDecoder(strings.NewReader(catalog), "/catalog/book",
+		Options{InferTypes: true, AttrPrefix: DefaultAttrPrefix})
+	want := []map[string]interface{}{
+		{"@id": "1", "title": "Go", "price": int64(10)},
+		{"@id": "2", "title": "XML", "price": 12.5},
+	}
+	for _, w := range want {
+		got, err := rd.Next()
+		if err != nil {
+			t.Fatalf("Next() error = %v", err)
+		}
+		if !reflect.DeepEqual(got, w) {
+			t.Errorf("Next() = %#v, want %#v", got, w)
+		}
+	}
+	if _, err := rd.Next(); err != io.EOF {
+		t.Errorf("Next() error = %v, want %v", err, io.EOF)
+	}
+}
+
+func TestRecordDecoder_Decode(t *testing.T) {
+	rd := NewRecordDecoder(strings.NewReader(catalog), "catalog/shelf/book", Options{})
+	doc := &Document{Ordered: true}
+	if err := rd.Decode(doc); err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	want := ordered.Map{{Key: "title", Value: "ignored"}}
+	if !reflect.DeepEqual(doc.Value, want) {
+		t.Errorf("Decode() = %#v, want %#v", doc.Value, want)
+	}
+	if err := rd.Decode(doc); err != io.EOF {
+		t.Errorf("Decode() error = %v, want %v", err, io.EOF)
+	}
+}
+
+func TestRecordDecoder_Text(t *testing.T) {
+	rd := NewRecordDecoder(strings.NewReader(`<c><name>bob</name><name>al</name></c>`), "/c/name", Options{})
+	for _, name := range []string{"bob", "al"} {
+		got, err := rd.Next()
+		if err != nil {
+			t.Fatalf("Next() error = %v", err)
+		}
+		if want := map[string]interface{}{DefaultTextKey: name}; !reflect.DeepEqual(got, want) {
+			t.Errorf("Next() = %#v, want %#v", got, want)
+		}
+		out, err := Marshal(&Document{Options: Options{Root: "name"}, Value: got})
+		if err != nil {
+			t.Fatalf("Marshal() error = %v", err)
+		}
+		if want := "<name>" + name + "</name>"; string(out) != want {
+			t.Errorf("Marshal() = %s, want %s", out, want)
+		}
+	}
+	if _, err := rd.Next(); err != io.EOF {
+		t.Errorf("Next() error = %v, want %v", err, io.EOF)
+	}
+}
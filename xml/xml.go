@@ -15,18 +15,172 @@
 package xml
 
 import (
+	"bytes"
 	xmle "encoding/xml"
 	"errors"
 	"fmt"
 	"io"
 	"sort"
+	"strconv"
 	"strings"
+
+	"github.com/99nil/ditto/ordered"
 )
 
 type Map = xml
 
 type xml map[string]interface{}
 
+// OrderedMap is like Map but keeps the document order of elements.
+type OrderedMap ordered.Map
+
+// Options configures how documents are decoded and encoded.
+type Options struct {
+	// InferTypes decodes leaf values looking like integers, floats, booleans or null
+	// as int64, float64, bool or nil instead of strings.
+	InferTypes bool
+	// TypeAttributes adds a type attribute, such as type="int",
+	// to the elements of non-string scalar values when encoding,
+	// so that decoding restores their types.
+	TypeAttributes bool
+	// AttrPrefix, when not empty, keeps the attributes of elements as keys made of
+	// the prefix and the attribute name, such as "@id", and encodes such keys back as attributes.
+	AttrPrefix string
+	// TextKey is the key holding the character data of the root element, and of elements
+	// with attributes, child elements or markup when AttrPrefix or Markup is set, DefaultTextKey when empty.
+	TextKey string
+	// Namespaces selects how namespaced names are written as keys, NamespaceNone by default.
+	Namespaces NamespaceMode
+	// Prefixes maps prefixes to the namespace URIs declared on the root element when encoding,
+	// the empty prefix declaring the default namespace.
+	Prefixes map[string]string
+	// Root is the name of the root element of a Document when encoding, xml when empty.
+	Root string
+	// Indent is the string indenting nested elements when encoding, four spaces when empty.
+	Indent string
+	// Compact encodes documents without indentation nor line breaks, ignoring Indent.
+	Compact bool
+	// Declaration writes the <?xml version="1.0" encoding="UTF-8"?> declaration
+	// before the root element, when the Document is encoded by Marshal or an Encoder of this package.
+	Declaration bool
+	// Strict rejects mixed content, character data next to child elements,
+	// and repeated sibling elements not marked as array members when decoding,
+	// instead of decoding repeated siblings as arrays.
+	Strict bool
+	// Markup keeps the CDATA sections, comments and processing instructions of elements
+	// as CDataKey, CommentKey and ProcInstKey keys, those before the root element under PrologKey,
+	// and encodes them back along with strings containing markup characters as CDATA sections.
+	// CDATA sections are told apart from text only by Unmarshal and the Decoder of this package.
+	Markup bool
+	// ForceArray lists the paths of the elements always decoded as array members,
+	// even when they appear once, such as "/rss/channel/item" starting with the root element.
+	ForceArray []string
+	// SelfClosing writes empty elements as self-closing tags, such as <a/>,
+	// when the Document is encoded by Marshal or an Encoder of this package.
+	SelfClosing bool
+	// MixedContent keeps the content of elements having both text and child elements,
+	// such as <p>Hello <b>world</b>!</p>, as a ContentKey list of the text strings
+	// and single-key maps of the child elements in order, and encodes such lists back,
+	// without indentation inside the element. Strict then accepts mixed content.
+	MixedContent bool
+}
+
+var (
+	// ErrMixedContent reports character data next to child elements in strict mode.
+	ErrMixedContent = errors.New("mixed content")
+	// ErrDuplicateElement reports repeated sibling elements not marked as array members in strict mode.
+	ErrDuplicateElement = errors.New("duplicate element")
+	// ErrConflict reports an element whose value cannot be merged with the previous ones.
+	ErrConflict = errors.New("conflicting element")
+)
+
+// Conventional keys of attributes and character data, in the style of xml2js.
+const (
+	DefaultAttrPrefix = "@"
+	DefaultTextKey    = "#text"
+)
+
+// Keys holding the CDATA sections, comments and processing instructions of an element
+// when Options.Markup is set, repeated ones making an array.
+const (
+	CDataKey    = "#cdata"
+	CommentKey  = "#comment"
+	ProcInstKey = "#pi"
+)
+
+// PrologKey is the key of the root element holding the comments and processing instructions
+// written before it when Options.Markup is set, a list of maps with a CommentKey or ProcInstKey each.
+const PrologKey = "#prolog"
+
+// ContentKey is the key holding the text and child elements of an element with mixed content
+// when Options.MixedContent is set.
+const ContentKey = "#content"
+
+// hasTextKey reports whether character data can be held by a key next to other ones.
+func (o *Options) hasTextKey() bool {
+	return o.AttrPrefix != "" || o.Markup
+}
+
+// isMarkupKey reports whether key holds CDATA sections, comments or processing instructions.
+func (o *Options) isMarkupKey(key string) bool {
+	return o.Markup && (key == CDataKey || key == CommentKey || key == ProcInstKey)
+}
+
+// textKey returns the key holding character data.
+func (o *Options) textKey() string {
+	if o.TextKey == "" {
+		return DefaultTextKey
+	}
+	return o.TextKey
+}
+
+// attrKey returns the key holding the attribute name.
+func (o *Options) attrKey(name xmle.Name, ns namespaces) string {
+	return o.AttrPrefix + o.key(name, ns)
+}
+
+// attrName returns the attribute name held by key, if any.
+func (o *Options) attrName(key string) (string, bool) {
+	if o.AttrPrefix == "" || len(key) <= len(o.AttrPrefix) || !strings.HasPrefix(key, o.AttrPrefix) {
+		return "", false
+	}
+	return key[len(o.AttrPrefix):], true
+}
+
+// Document decodes and encodes a document according to Options.
+// Value holds a map[string]interface{}, or an ordered.Map when Ordered is set.
+// When encoded as a root value, the element is named xml like a Map.
+type Document struct {
+	Options Options
+	Ordered bool
+	Value   interface{}
+
+	// the whole document when decoded by Unmarshal
+	src []byte
+}
+
+// defaultRoot is the root element name given by the encoder to a Map.
+const defaultRoot = "xml"
+
+// typeAttr is the name of the attribute holding the type of an element.
+const typeAttr = "type"
+
+// Values of the type attribute.
+const (
+	TypeArray  = "array"
+	TypeString = "string"
+	TypeInt    = "int"
+	TypeFloat  = "float"
+	TypeBool   = "bool"
+	TypeNull   = "null"
+	// TypeList marks a member of an array which is itself an array,
+	// its members being the listItem child elements.
+	TypeList = "list"
+)
+
+// listItem is the name of the elements holding the members of a nested array.
+const listItem = "item"
+
 type xmlData struct {
 	XMLName xmle.Name
 	Attr    []xmle.Attr `xml:",attr"`
@@ -35,96 +189,475 @@ type xmlData struct {
 
 var arrayAttr = xmle.Attr{
 	Name: xmle.Name{
-		Local: "type",
+		Local: typeAttr,
 	},
-	Value: "array",
+	Value: TypeArray,
+}
+
+var listAttr = xmle.Attr{
+	Name: xmle.Name{
+		Local: typeAttr,
+	},
+	Value: TypeList,
+}
+
+type encoder struct {
+	e    *xmle.Encoder
+	opts *Options
+	// namespace declarations of the elements being encoded
+	scopes   [][]xmle.Attr
+	prefixes int
 }
 
-func sortXML(e *xmle.Encoder, data xml) error {
+// root encodes data as the root element start.
+func (enc *encoder) root(start xmle.StartElement, data interface{}) error {
+	switch val := data.(type) {
+	case map[string]interface{}:
+		if len(val) == 0 {
+			return nil
+		}
+	case ordered.Map:
+		if len(val) == 0 {
+			return nil
+		}
+	}
+	switch {
+	case enc.opts.Compact:
+	case enc.opts.Indent != "":
+		enc.e.Indent("", enc.opts.Indent)
+	default:
+		enc.e.Indent("", "    ")
+	}
+	if enc.opts.Markup {
+		var nodes interface{}
+		data, nodes = splitProlog(data)
+		if err := enc.prolog(nodes); err != nil {
+			return err
+		}
+	}
+	return enc.element(start, data)
+}
+
+func sortXML(data map[string]interface{}) []string {
 	keys := make([]string, 0, len(data))
 	for k, _ := range data {
 		keys = append(keys, k)
 	}
 	sort.Sort(sort.StringSlice(keys))
-	var err error
-	for _, key := range keys {
-		v := data[key]
-		switch val := v.(type) {
-		case map[string]interface{}:
-			err = e.EncodeElement(xml(val), xmle.StartElement{
-				Name: xmle.Name{
-					Local: key,
-				},
-			})
-		case []interface{}:
-			err = encodeSlice(e, key, val)
-		default:
-			err = e.Encode(xmlData{
-				XMLName: xmle.Name{
-					Local: key,
-				},
-				Value: val,
+	return keys
+}
+
+// element encodes v as the element start.
+func (enc *encoder) element(start xmle.StartElement, v interface{}) error {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		items := make(ordered.Map, 0, len(val))
+		for _, key := range sortXML(val) {
+			items = append(items, ordered.Item{Key: key, Value: val[key]})
+		}
+		return enc.items(start, items)
+	case ordered.Map:
+		return enc.items(start, val)
+	default:
+		if typ := scalarType(val); enc.opts.TypeAttributes && typ != "" && !hasAttr(start.Attr, typeAttr) {
+			start.Attr = append(start.Attr, xmle.Attr{Name: xmle.Name{Local: typeAttr}, Value: typ})
+		}
+		start = enc.declare(start)
+		defer enc.undeclare()
+		if s, ok := val.(string); ok && enc.opts.Markup && hasMarkupChars(s) {
+			return enc.e.Encode(cdataData{
+				XMLName: start.Name,
+				Attr:    start.Attr,
+				Value:   s,
 			})
 		}
-		if err != nil {
+		return enc.e.Encode(xmlData{
+			XMLName: start.Name,
+			Attr:    start.Attr,
+			Value:   val,
+		})
+	}
+}
+
+// items encodes the items of a map as the element start,
+// attribute and character data keys being encoded as such.
+func (enc *encoder) items(start xmle.StartElement, items ordered.Map) error {
+	if len(items) == 0 {
+		return nil
+	}
+	var (
+		fields     = make(ordered.Map, 0, len(items))
+		text       interface{}
+		hasText    bool
+		hasMarkup  bool
+		content    interface{}
+		hasContent bool
+	)
+	for _, item := range items {
+		if name, ok := enc.opts.attrName(item.Key); ok {
+			start.Attr = append(start.Attr, xmle.Attr{Name: xmle.Name{Local: name}, Value: textOf(item.Value)})
+			continue
+		}
+		if enc.opts.isDeclarationKey(item.Key) {
+			start.Attr = append(start.Attr, xmle.Attr{Name: xmle.Name{Local: item.Key}, Value: textOf(item.Value)})
+			continue
+		}
+		if enc.opts.MixedContent && item.Key == ContentKey {
+			content, hasContent = item.Value, true
+			continue
+		}
+		// the text of the root element is held by the text key in any case
+		if (enc.opts.hasTextKey() || len(enc.scopes) == 0) && item.Key == enc.opts.textKey() {
+			text, hasText = item.Value, true
+		}
+		hasMarkup = hasMarkup || enc.opts.isMarkupKey(item.Key)
+		fields = append(fields, item)
+	}
+	if hasContent {
+		return enc.mixed(start, content, fields)
+	}
+	if hasMarkup {
+		return enc.content(start, fields)
+	}
+	if hasText {
+		children := fields[:0]
+		for _, item := range fields {
+			if item.Key != enc.opts.textKey() {
+				children = append(children, item)
+			}
+		}
+		fields = children
+	}
+	if hasText && len(fields) == 0 {
+		return enc.element(start, text)
+	}
+	start = enc.declare(start)
+	defer enc.undeclare()
+	if err := enc.e.EncodeToken(start); err != nil {
+		return err
+	}
+	if hasText {
+		if err := enc.e.EncodeToken(xmle.CharData(textOf(text))); err != nil {
 			return err
 		}
 	}
-	return err
+	for _, item := range fields {
+		if err := enc.field(item.Key, item.Value); err != nil {
+			return err
+		}
+	}
+	return enc.e.EncodeToken(start.End())
 }
 
-func encodeSlice(e *xmle.Encoder, key string, data []interface{}) error {
-	var err error
-	for _, v := range data {
-		switch value := v.(type) {
-		case map[string]interface{}:
-			err = e.EncodeElement(xml(value), xmle.StartElement{
-				Name: xmle.Name{
-					Local: key,
-				},
-				Attr: []xmle.Attr{arrayAttr},
-			})
-		case []interface{}:
-		default:
-			err = e.Encode(xmlData{
-				XMLName: xmle.Name{
-					Local: key,
-				},
-				Attr:  []xmle.Attr{arrayAttr},
-				Value: v,
-			})
+// textOf formats the scalar v as character data.
+func textOf(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	return fmt.Sprint(v)
+}
+
+func hasAttr(attrs []xmle.Attr, local string) bool {
+	for _, attr := range attrs {
+		if attr.Name.Local == local && attr.Name.Space == "" {
+			return true
 		}
-		if err != nil {
+	}
+	return false
+}
+
+// field encodes the value of key, an array being encoded as repeated elements.
+func (enc *encoder) field(key string, v interface{}) error {
+	start := xmle.StartElement{
+		Name: xmle.Name{
+			Local: key,
+		},
+	}
+	arr, ok := v.([]interface{})
+	if !ok {
+		return enc.element(start, v)
+	}
+	for _, elem := range arr {
+		if err := enc.member(start, elem); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// member encodes v as the element start of an array member.
+// Nested arrays are encoded as list elements and empty maps as empty elements,
+// so that every member keeps its position.
+func (enc *encoder) member(start xmle.StartElement, v interface{}) error {
+	start.Attr = []xmle.Attr{arrayAttr}
+	switch val := v.(type) {
+	case []interface{}:
+		start.Attr = []xmle.Attr{listAttr}
+		start = enc.declare(start)
+		defer enc.undeclare()
+		if err := enc.e.EncodeToken(start); err != nil {
+			return err
+		}
+		if err := enc.field(listItem, val); err != nil {
 			return err
 		}
+		return enc.e.EncodeToken(start.End())
+	case map[string]interface{}:
+		if len(val) == 0 {
+			return enc.empty(start)
+		}
+	case ordered.Map:
+		if len(val) == 0 {
+			return enc.empty(start)
+		}
 	}
-	return err
+	return enc.element(start, v)
+}
+
+func (enc *encoder) empty(start xmle.StartElement) error {
+	start = enc.declare(start)
+	defer enc.undeclare()
+	if err := enc.e.EncodeToken(start); err != nil {
+		return err
+	}
+	return enc.e.EncodeToken(start.End())
+}
+
+// scalarType returns the type attribute value of v, empty for strings.
+func scalarType(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return TypeNull
+	case bool:
+		return TypeBool
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return TypeInt
+	case float32, float64:
+		return TypeFloat
+	}
+	return ""
 }
 
 func (m xml) MarshalXML(e *xmle.Encoder, start xmle.StartElement) error {
-	if len(m) == 0 {
-		return nil
+	return (&encoder{e: e, opts: &Options{}}).root(start, map[string]interface{}(m))
+}
+
+func (m OrderedMap) MarshalXML(e *xmle.Encoder, start xmle.StartElement) error {
+	// use the same root element as Map
+	if start.Name.Local == "OrderedMap" {
+		start.Name.Local = defaultRoot
 	}
-	e.Indent("", "    ")
+	return (&encoder{e: e, opts: &Options{}}).root(start, ordered.Map(m))
+}
+
+func (doc Document) MarshalXML(e *xmle.Encoder, start xmle.StartElement) error {
+	switch {
+	case doc.Options.Root != "":
+		start.Name = xmle.Name{Local: doc.Options.Root}
+	case start.Name.Local == "Document":
+		start.Name.Local = defaultRoot
+	}
+	return (&encoder{e: e, opts: &doc.Options}).root(start, doc.Value)
+}
+
+func (m xml) UnmarshalXML(d *xmle.Decoder, start xmle.StartElement) error {
+	return m.unmarshal(d, start, &Options{}, nil, nil, nil)
+}
 
-	err := e.EncodeToken(start)
-	if err != nil {
+func (m *OrderedMap) UnmarshalXML(d *xmle.Decoder, start xmle.StartElement) error {
+	data := make(xml)
+	order := make(map[string]int)
+	if err := data.unmarshal(d, start, &Options{}, order, nil, nil); err != nil {
 		return err
 	}
+	*m = OrderedMap(orderMap(data, "", order))
+	return nil
+}
 
-	if err := sortXML(e, m); err != nil {
+func (doc *Document) UnmarshalXML(d *xmle.Decoder, start xmle.StartElement) error {
+	data := make(xml)
+	var order map[string]int
+	if doc.Ordered {
+		order = make(map[string]int)
+	}
+	if err := data.unmarshal(d, start, &doc.Options, order, nil, doc.src); err != nil {
 		return err
 	}
-	return e.EncodeToken(start.End())
+	if doc.Ordered {
+		doc.Value = orderMap(data, "", order)
+	} else {
+		doc.Value = map[string]interface{}(data)
+	}
+	return nil
 }
 
-func (m xml) UnmarshalXML(d *xmle.Decoder, start xmle.StartElement) error {
+// orderMap converts data to an ordered.Map whose keys follow
+// the first appearance of their element path in order.
+func orderMap(data map[string]interface{}, path string, order map[string]int) ordered.Map {
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.SliceStable(keys, func(i, j int) bool {
+		return order[path+keys[i]] < order[path+keys[j]]
+	})
+	m := make(ordered.Map, 0, len(keys))
+	for _, key := range keys {
+		sub := path + key + "."
+		if key == ContentKey {
+			// the nodes of mixed content hold the child elements
+			sub = path
+		}
+		m = append(m, ordered.Item{Key: key, Value: orderValue(data[key], sub, order)})
+	}
+	return m
+}
+
+func orderValue(v interface{}, path string, order map[string]int) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		return orderMap(val, path, order)
+	case []interface{}:
+		arr := make([]interface{}, 0, len(val))
+		for _, elem := range val {
+			arr = append(arr, orderValue(elem, path, order))
+		}
+		return arr
+	}
+	return v
+}
+
+// unmarshal decodes the content of start into m,
+// recording the first appearance of every element path in order when it is not nil.
+// ns holds the namespaces declared by the ancestors of start, none for the root element,
+// and src the whole document read by d when known.
+func (m xml) unmarshal(d *xmle.Decoder, start xmle.StartElement, opts *Options, order map[string]int, ns namespaces, src []byte) error {
 	var (
 		indexArr []string
+		types    []string
+		members  []bool
+		// whether the elements have attributes or child elements kept as keys
+		keyed    []bool
+		children []bool
+		// whether the elements have CDATA sections, comments or processing instructions
+		marked   []bool
 		charData []byte
 		akc      = make(map[string]int)
+		// the array membership of the child elements met by each element, the root first
+		siblings = []map[string]bool{{}}
+		// the content of each element in order when keeping mixed content, the root first
+		contents = [][]node{nil}
+		forced   map[string]bool
 	)
+	// add appends a part of the content of the current element when keeping mixed content.
+	add := func(n node) {
+		if !opts.MixedContent {
+			return
+		}
+		last := len(contents) - 1
+		if n.key == "" && len(contents[last]) > 0 {
+			if prev := &contents[last][len(contents[last])-1]; prev.key == "" {
+				prev.text += n.text
+				return
+			}
+		}
+		contents[last] = append(contents[last], n)
+	}
+	// record notes the first appearance of the element path keys in order.
+	record := func(keys []string) {
+		if order == nil {
+			return
+		}
+		path := strings.Join(keys, ".")
+		if _, ok := order[path]; !ok {
+			order[path] = len(order)
+		}
+	}
+	// declarations keeps the namespace declarations as keys of the root when attributes are dropped,
+	// so that the prefixes of the keys stay bound: all of those of the root,
+	// and the prefixed ones of the other elements unless the prefix is already declared.
+	declarations := func(root bool, attr []xmle.Attr) {
+		for _, a := range attr {
+			if !isDeclaration(a) || (!root && a.Name.Space != xmlnsPrefix) {
+				continue
+			}
+			key := opts.key(a.Name, ns)
+			if _, ok := m[key]; ok {
+				continue
+			}
+			record([]string{key})
+			m[key] = a.Value
+		}
+	}
+	// attrs inserts the attributes of the element path keys, other than type hints,
+	// and reports whether there were any.
+	attrs := func(keys []string, attr []xmle.Attr) (bool, error) {
+		if opts.AttrPrefix == "" {
+			if opts.Namespaces == NamespacePrefix {
+				declarations(len(keys) == 0, attr)
+			}
+			return false, nil
+		}
+		found := false
+		for _, a := range attr {
+			if a.Name.Local == typeAttr && a.Name.Space == "" && isType(a.Value) {
+				continue
+			}
+			if opts.Namespaces == NamespaceURI && isDeclaration(a) {
+				continue
+			}
+			path := append(keys[:len(keys):len(keys)], opts.attrKey(a.Name, ns))
+			record(path)
+			if err := loop(m, a.Value, path, akc, 0); err != nil {
+				return false, fmt.Errorf("attribute %s: %w", strings.Join(path, "."), err)
+			}
+			found = true
+		}
+		return found, nil
+	}
+	// special inserts a key of the current element other than attributes, repeated ones making an array.
+	special := func(key string, value interface{}) error {
+		path := append(indexArr[:len(indexArr):len(indexArr)], key)
+		if _, repeated := siblings[len(siblings)-1][key]; repeated {
+			if akc[strings.Join(path, ".")] == 0 {
+				toArray(m, path, akc)
+			}
+			akc[strings.Join(path, ".")]++
+		}
+		siblings[len(siblings)-1][key] = false
+		record(path)
+		if len(marked) > 0 {
+			marked[len(marked)-1] = true
+		}
+		return loop(m, value, path, akc, 0)
+	}
+	isRoot := len(ns) == 0
+	if opts.Markup && isRoot && src != nil {
+		var nodes []interface{}
+		for _, token := range prolog(src) {
+			if key, value, ok := markup(token); ok {
+				nodes = append(nodes, map[string]interface{}{key: value})
+			}
+		}
+		if len(nodes) > 0 {
+			record([]string{PrologKey})
+			m[PrologKey] = nodes
+		}
+	}
+	ns.push(start.Attr)
+	if _, err := attrs(nil, start.Attr); err != nil {
+		return err
+	}
+	if len(opts.ForceArray) > 0 {
+		forced = make(map[string]bool, len(opts.ForceArray))
+		root := "/" + opts.key(start.Name, ns)
+		for _, path := range opts.ForceArray {
+			forced[strings.TrimPrefix(path, root)] = true
+		}
+	}
+
 	for {
+		offset := d.InputOffset()
 		token, err := d.Token()
 		if err == io.EOF {
 			break
@@ -135,58 +668,389 @@ func (m xml) UnmarshalXML(d *xmle.Decoder, start xmle.StartElement) error {
 
 		switch tv := token.(type) {
 		case xmle.StartElement:
-			isArray := false
+			isArray, isList := false, false
+			typ := ""
 			for _, attr := range tv.Attr {
-				if attr == arrayAttr {
+				if attr.Name.Local != typeAttr || attr.Name.Space != "" || !isType(attr.Value) {
+					continue
+				}
+				switch attr.Value {
+				case TypeArray:
 					isArray = true
-					break
+				case TypeList:
+					isList = true
+				default:
+					typ = attr.Value
 				}
 			}
-			indexArr = append(indexArr, tv.Name.Local)
+			if opts.Strict && !opts.MixedContent && len(bytes.TrimSpace(charData)) > 0 {
+				return fmt.Errorf("element %s: %w", pathString(indexArr), ErrMixedContent)
+			}
+			charData = nil
+			if len(children) > 0 {
+				children[len(children)-1] = true
+			}
+			ns.push(tv.Attr)
+			key := opts.key(tv.Name, ns)
+			path := append(indexArr[:len(indexArr):len(indexArr)], key)
+			if forced != nil && forced["/"+strings.Join(path, "/")] {
+				isArray = true
+			}
+			member := isArray || isList
+			add(node{key: key, member: member})
+			wasMember, repeated := siblings[len(siblings)-1][key]
+			switch {
+			case !repeated:
+			case opts.Strict && !(member && wasMember):
+				return fmt.Errorf("element %s: %w", strings.Join(path, "."), ErrDuplicateElement)
+			case !wasMember:
+				// the previous sibling becomes the first member of an array
+				toArray(m, path, akc)
+				isArray, member = !isList, true
+			case !member:
+				isArray, member = true, true
+			}
+			siblings[len(siblings)-1][key] = member
+			record(path)
+			if isList {
+				akc[strings.Join(path, ".")]++
+				value, err := list(d, tv, opts, order != nil, ns, src)
+				if err != nil {
+					return fmt.Errorf("element %s: %w", strings.Join(path, "."), err)
+				}
+				if err := loop(m, value, path, akc, 0); err != nil {
+					return fmt.Errorf("element %s: %w", strings.Join(path, "."), err)
+				}
+				ns.pop()
+				continue
+			}
 			if isArray {
-				akc[strings.Join(indexArr, ".")]++
+				akc[strings.Join(path, ".")]++
+			}
+			indexArr = path
+			types = append(types, typ)
+			members = append(members, isArray)
+			hasAttrs, err := attrs(indexArr, tv.Attr)
+			if err != nil {
+				return err
 			}
+			keyed = append(keyed, hasAttrs)
+			children = append(children, false)
+			marked = append(marked, false)
+			siblings = append(siblings, map[string]bool{})
+			contents = append(contents, nil)
 		case xmle.CharData:
-			charData = tv.Copy()
-		case xmle.EndElement:
-			if tv.Name.Local == start.Name.Local {
-				break
+			if opts.Markup && isCDATA(src, offset, d.InputOffset()) {
+				if err := special(CDataKey, string(tv)); err != nil {
+					return fmt.Errorf("element %s: %w", pathString(indexArr), err)
+				}
+				add(node{key: CDataKey})
+				continue
+			}
+			add(node{text: string(tv)})
+			if opts.hasTextKey() && len(indexArr) > 0 && len(bytes.TrimSpace(tv)) > 0 {
+				record(append(indexArr[:len(indexArr):len(indexArr)], opts.textKey()))
+			}
+			charData = append(charData, tv...)
+		case xmle.Comment, xmle.ProcInst:
+			if key, value, ok := markup(tv); ok && opts.Markup {
+				if err := special(key, value); err != nil {
+					return fmt.Errorf("element %s: %w", pathString(indexArr), err)
+				}
+				add(node{key: key})
 			}
+		case xmle.EndElement:
+			// the end of start finishes the element
 			if len(indexArr) == 0 {
-				return errors.New("format error, expect startElement")
+				if isMixed(contents[0]) {
+					record([]string{ContentKey})
+					return mixed(m, nil, contents[0], akc)
+				}
+				if opts.Strict && len(bytes.TrimSpace(charData)) > 0 && len(siblings[0]) > 0 {
+					return fmt.Errorf("element %s: %w", pathString(indexArr), ErrMixedContent)
+				}
+				// the text of the root is held by the text key, dropped next to child elements without one
+				if len(bytes.TrimSpace(charData)) == 0 || (len(siblings[0]) > 0 && !opts.hasTextKey()) {
+					return nil
+				}
+				value, err := scalar(charData, "", opts.InferTypes)
+				if err != nil {
+					return fmt.Errorf("element %s: %w", pathString(indexArr), err)
+				}
+				record([]string{opts.textKey()})
+				return loop(m, value, []string{opts.textKey()}, akc, 0)
 			}
 			indexArrLen := len(indexArr)
 			end := indexArr[indexArrLen-1]
-			if end != tv.Name.Local {
+			if end != opts.key(tv.Name, ns) {
 				return errors.New("format error, expect current endElement")
 			}
 
-			if charData != nil {
-				loop(m, string(charData), indexArr, akc, 0)
+			typ := types[indexArrLen-1]
+			hasChildren := children[indexArrLen-1]
+			hasMarkup := marked[indexArrLen-1]
+			if isMixed(contents[indexArrLen]) {
+				record(append(indexArr[:indexArrLen:indexArrLen], ContentKey))
+				if err := mixed(m, indexArr, contents[indexArrLen], akc); err != nil {
+					return fmt.Errorf("element %s: %w", strings.Join(indexArr, "."), err)
+				}
+				// the text is held by the content
+				charData, typ, hasChildren = nil, "", true
+			}
+			if hasMarkup && len(bytes.TrimSpace(charData)) == 0 {
 				charData = nil
 			}
+			if hasChildren {
+				switch {
+				case len(bytes.TrimSpace(charData)) == 0:
+					// the indentation following the last child element is not data
+					charData = nil
+				case opts.Strict:
+					return fmt.Errorf("element %s: %w", strings.Join(indexArr, "."), ErrMixedContent)
+				case !opts.hasTextKey():
+					// without a key for it, the text of mixed content is dropped
+					charData = nil
+				}
+			}
+			// an empty array member keeps its position
+			empty := members[indexArrLen-1] && !hasChildren && !keyed[indexArrLen-1] && !hasMarkup
+			if charData != nil || typ == TypeNull || empty {
+				value, err := scalar(charData, typ, opts.InferTypes)
+				if err != nil {
+					return fmt.Errorf("element %s: %w", strings.Join(indexArr, "."), err)
+				}
+				keys := indexArr
+				if opts.hasTextKey() && (keyed[indexArrLen-1] || hasChildren || hasMarkup) {
+					keys = append(indexArr[:indexArrLen:indexArrLen], opts.textKey())
+					record(keys)
+				}
+				if err := loop(m, value, keys, akc, 0); err != nil {
+					return fmt.Errorf("element %s: %w", strings.Join(indexArr, "."), err)
+				}
+			}
+			charData = nil
+			types = types[:indexArrLen-1]
+			members = members[:indexArrLen-1]
+			keyed = keyed[:indexArrLen-1]
+			children = children[:indexArrLen-1]
+			marked = marked[:indexArrLen-1]
+			// the array members of the next element with the same path are counted from zero
+			prefix := strings.Join(indexArr, ".") + "."
+			for key := range siblings[indexArrLen] {
+				delete(akc, prefix+key)
+			}
+			siblings = siblings[:indexArrLen]
+			contents = contents[:indexArrLen]
 			indexArr = indexArr[:indexArrLen-1]
+			ns.pop()
 		}
 	}
 	return nil
 }
 
+// toArray turns the value of the element path, if any, into the first member of an array.
+func toArray(m xml, path []string, akc map[string]int) {
+	parent, ok := lookup(m, path[:len(path)-1], akc)
+	if !ok {
+		return
+	}
+	key := path[len(path)-1]
+	if v, ok := parent[key]; ok {
+		parent[key] = []interface{}{v}
+		akc[strings.Join(path, ".")] = 1
+	}
+}
+
+// lookup returns the map holding the child elements of the current element with the path.
+func lookup(m xml, path []string, akc map[string]int) (map[string]interface{}, bool) {
+	parent := map[string]interface{}(m)
+	for i, key := range path {
+		v := parent[key]
+		if n := akc[strings.Join(path[:i+1], ".")]; n > 0 {
+			arr, _ := v.([]interface{})
+			if len(arr) < n {
+				return nil, false
+			}
+			v = arr[n-1]
+		}
+		next, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		parent = next
+	}
+	return parent, true
+}
+
+// node is a part of the content of an element, text when key is empty.
+type node struct {
+	text string
+	key  string
+	// whether the child element is an array member by itself, rather than by repetition
+	member bool
+}
+
+// isMixed reports whether the content holds both text other than whitespace and child elements.
+func isMixed(content []node) bool {
+	hasText, hasChildren := false, false
+	for _, n := range content {
+		switch {
+		case n.key == "":
+			hasText = hasText || strings.TrimSpace(n.text) != ""
+		case n.key != CDataKey && n.key != CommentKey && n.key != ProcInstKey:
+			hasChildren = true
+		}
+	}
+	return hasText && hasChildren
+}
+
+// mixed moves the child elements and markup of the current element with the path
+// to a ContentKey list holding them in order along with the text.
+func mixed(m xml, path []string, content []node, akc map[string]int) error {
+	elem, _ := lookup(m, path, akc)
+	counts := make(map[string]int)
+	for _, n := range content {
+		if n.key != "" {
+			counts[n.key]++
+		}
+	}
+	seen := make(map[string]int)
+	list := make([]interface{}, 0, len(content))
+	for _, n := range content {
+		if n.key == "" {
+			list = append(list, n.text)
+			continue
+		}
+		// an empty child element holds no value
+		var value interface{} = ""
+		if v, ok := elem[n.key]; ok {
+			value = v
+			if arr, ok := v.([]interface{}); ok && (counts[n.key] > 1 || n.member) {
+				value = nil
+				if i := seen[n.key]; i < len(arr) {
+					value = arr[i]
+				}
+				if n.member {
+					value = []interface{}{value}
+				}
+			}
+		}
+		seen[n.key]++
+		list = append(list, map[string]interface{}{n.key: value})
+	}
+	for key := range counts {
+		delete(elem, key)
+	}
+	return loop(m, list, append(path[:len(path):len(path)], ContentKey), akc, 0)
+}
+
+// pathString returns the path of an element for error messages, the root being empty.
+func pathString(keys []string) string {
+	if len(keys) == 0 {
+		return "root"
+	}
+	return strings.Join(keys, ".")
+}
+
+// list decodes the members of the list element start.
+func list(d *xmle.Decoder, start xmle.StartElement, opts *Options, ordered bool, ns namespaces, src []byte) (interface{}, error) {
+	items := make(xml)
+	var order map[string]int
+	if ordered {
+		order = make(map[string]int)
+	}
+	if err := items.unmarshal(d, start, opts, order, ns[:len(ns)-1:len(ns)-1], src); err != nil {
+		return nil, err
+	}
+	arr, ok := items[listItem].([]interface{})
+	if !ok {
+		arr = make([]interface{}, 0)
+	}
+	if ordered {
+		return orderValue(arr, listItem+".", order), nil
+	}
+	return arr, nil
+}
+
+// isType reports whether typ is a value of the type attribute.
+func isType(typ string) bool {
+	switch strings.ToLower(typ) {
+	case TypeArray, TypeList, TypeString, TypeInt, "integer", "long", TypeFloat, "double", "decimal", "number",
+		TypeBool, "boolean", TypeNull:
+		return true
+	}
+	return false
+}
+
+// scalar converts the character data of an element to the type named by typ,
+// or to the inferred type when typ is unknown and infer is set.
+func scalar(data []byte, typ string, infer bool) (interface{}, error) {
+	s := string(data)
+	text := strings.TrimSpace(s)
+	switch strings.ToLower(typ) {
+	case TypeString:
+		return s, nil
+	case TypeInt, "integer", "long":
+		return strconv.ParseInt(text, 10, 64)
+	case TypeFloat, "double", "decimal", "number":
+		return strconv.ParseFloat(text, 64)
+	case TypeBool, "boolean":
+		return strconv.ParseBool(text)
+	case TypeNull:
+		return nil, nil
+	}
+	if !infer {
+		return s, nil
+	}
+	switch text {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	}
+	if i, err := strconv.ParseInt(text, 10, 64); err == nil && isDecimal(text) {
+		return i, nil
+	}
+	if f, err := strconv.ParseFloat(text, 64); err == nil && isDecimal(text) {
+		return f, nil
+	}
+	return s, nil
+}
+
+// isDecimal reports whether s is written with decimal digits only,
+// rejecting forms such as "Inf", "NaN", "0x1p-2" or "1_000" accepted by strconv.
+func isDecimal(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c >= '0' && c <= '9':
+		case c == '-' || c == '+' || c == '.' || c == 'e' || c == 'E':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
 // 自动合并数组
-func loop(data interface{}, value string, keys []string, akc map[string]int, index int) {
+func loop(data interface{}, value interface{}, keys []string, akc map[string]int, index int) error {
 	// 判断数据类型
 	switch dv := data.(type) {
 	case map[string]interface{}:
-		loopMap(dv, value, keys, akc, index)
+		return loopMap(dv, value, keys, akc, index)
 	case xml:
-		loopMap(dv, value, keys, akc, index)
+		return loopMap(dv, value, keys, akc, index)
 	case *[]interface{}:
-		loopSlice(dv, value, keys, akc, index)
-	default:
-		fmt.Printf("%T\n", data)
+		return loopSlice(dv, value, keys, akc, index)
 	}
+	return fmt.Errorf("%w: %s holds a value, not child elements", ErrConflict, strings.Join(keys[:index], "."))
 }
 
-func loopMap(data xml, value string, keys []string, akc map[string]int, index int) {
+func loopMap(data xml, value interface{}, keys []string, akc map[string]int, index int) error {
 	// 获取key剩余个数
 	extraLen := len(keys) - index
 	// 获取当前key
@@ -194,95 +1058,100 @@ func loopMap(data xml, value string, keys []string, akc map[string]int, index in
 	// 获取当前key路径
 	index++
 	pres := keys[:index]
+	isArray := akc[strings.Join(pres, ".")] > 0
+	dataVal, exists := data[key]
 	switch extraLen {
 	case 0:
-		return
+		return nil
 	case 1:
 		// 判断是否为数组
-		if akc[strings.Join(pres, ".")] > 0 {
+		if isArray {
 			// 判断值是否存在，不存在初始化
-			if dataVal, ok := data[key]; !ok {
-				data[key] = make([]interface{}, 0)
-			} else if _, ok = dataVal.([]interface{}); !ok {
-				data[key] = make([]interface{}, 0)
-			}
-			// 判断第一个值类型，不同则跳过赋值
-			if len(data[key].([]interface{})) > 0 {
-				switch data[key].([]interface{})[0].(type) {
-				case map[string]interface{}, []interface{}:
-					return
-				}
+			if !exists {
+				dataVal = make([]interface{}, 0)
 			}
-			data[key] = append(data[key].([]interface{}), value)
-			return
+			arr, ok := dataVal.([]interface{})
+			if !ok {
+				return fmt.Errorf("%w: %s is both an array member and not", ErrConflict, strings.Join(pres, "."))
+			}
+			// 判断当前元素是否已赋值
+			if len(arr) >= akc[strings.Join(pres, ".")] {
+				return fmt.Errorf("%w: %s has both a value and child elements", ErrConflict, strings.Join(pres, "."))
+			}
+			data[key] = append(arr, value)
+			return nil
 		}
 
-		if val, ok := data[key]; ok {
-			switch val.(type) {
-			case map[string]interface{}, []interface{}:
-				return
-			}
+		switch dataVal.(type) {
+		case map[string]interface{}, []interface{}:
+			return fmt.Errorf("%w: %s has both a value and child elements", ErrConflict, strings.Join(pres, "."))
 		}
 		data[key] = value
-	default:
-		// 判断value是否初始化
-		if dataVal, ok := data[key]; !ok {
-			// 未初始化value，判断该key是否为数组
-			if akc[strings.Join(pres, ".")] > 0 {
-				arr := make([]interface{}, 0)
-				loop(&arr, value, keys, akc, index)
-				data[key] = arr
-				return
-			}
-			data[key] = make(map[string]interface{})
-		} else if _, ok = dataVal.([]interface{}); ok {
-			// 已初始化数组，判断该key是否为数组
-			if akc[strings.Join(pres, ".")] > 0 {
-				arr := data[key].([]interface{})
-				loop(&arr, value, keys, akc, index)
-				data[key] = arr
-				return
-			}
-			data[key] = make(map[string]interface{})
-		} else if _, ok = dataVal.(map[string]interface{}); ok {
-			// 已初始化map，判断该key是否为数组
-			if akc[strings.Join(pres, ".")] > 0 {
-				arr := make([]interface{}, 0)
-				loop(&arr, value, keys, akc, index)
-				data[key] = arr
-				return
+		return nil
+	}
+
+	// 判断value是否初始化
+	switch dv := dataVal.(type) {
+	case nil:
+		if exists {
+			break
+		}
+		// 未初始化value，判断该key是否为数组
+		if isArray {
+			arr := make([]interface{}, 0)
+			if err := loop(&arr, value, keys, akc, index); err != nil {
+				return err
 			}
+			data[key] = arr
+			return nil
+		}
+		data[key] = make(map[string]interface{})
+	case []interface{}:
+		// 已初始化数组，判断该key是否为数组
+		if !isArray {
+			return fmt.Errorf("%w: %s is both an array member and not", ErrConflict, strings.Join(pres, "."))
+		}
+		if err := loop(&dv, value, keys, akc, index); err != nil {
+			return err
+		}
+		data[key] = dv
+		return nil
+	case map[string]interface{}:
+		// 已初始化map，判断该key是否为数组
+		if isArray {
+			return fmt.Errorf("%w: %s is both an array member and not", ErrConflict, strings.Join(pres, "."))
 		}
-		loop(data[key], value, keys, akc, index)
 	}
+	return loop(data[key], value, keys, akc, index)
 }
 
-func loopSlice(data *[]interface{}, value string, keys []string, akc map[string]int, index int) {
+func loopSlice(data *[]interface{}, value interface{}, keys []string, akc map[string]int, index int) error {
 	// 获取key剩余个数
 	extraLen := len(keys) - index
 	// 获取当前key路径
 	pres := keys[:index]
 	preArrNum := akc[strings.Join(pres, ".")]
-	pres = keys[:index+1]
 	switch extraLen {
 	case 0:
 		*data = append(*data, value)
-	default:
-		// 获取上次数组数量推演下标，并初始化补全
-		if preArrNum == 0 {
-			return
-		}
-		dataExtraLen := preArrNum - len(*data)
-		for i := 0; i < dataExtraLen; i++ {
-			*data = append(*data, struct{}{})
-		}
+		return nil
+	}
+	// 获取上次数组数量推演下标，并初始化补全
+	if preArrNum == 0 {
+		return fmt.Errorf("%w: %s is not an array member", ErrConflict, strings.Join(pres, "."))
+	}
+	dataExtraLen := preArrNum - len(*data)
+	for i := 0; i < dataExtraLen; i++ {
+		*data = append(*data, nil)
+	}
 
-		switch (*data)[preArrNum-1].(type) {
-		case map[string]interface{}:
-		default:
-			// 重赋值为map
-			(*data)[preArrNum-1] = make(map[string]interface{})
-		}
-		loop((*data)[preArrNum-1], value, keys, akc, index)
+	switch (*data)[preArrNum-1].(type) {
+	case map[string]interface{}:
+	case nil:
+		// 重赋值为map
+		(*data)[preArrNum-1] = make(map[string]interface{})
+	default:
+		return fmt.Errorf("%w: %s has both a value and child elements", ErrConflict, strings.Join(pres, "."))
 	}
+	return loop((*data)[preArrNum-1], value, keys, akc, index)
 }
@@ -0,0 +1,824 @@
+// Package xml
+// Copyright © 2021 zc2638 <zc2638@qq.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package xml
+
+import (
+	"bytes"
+	xmle "encoding/xml"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/99nil/ditto/ordered"
+)
+
+func TestDocument_UnmarshalXML(t *testing.T) {
+	const data = `
+<xml>
+    <count>5000</count>
+    <ratio>0.5</ratio>
+    <enabled>true</enabled>
+    <missing>null</missing>
+    <version>1.10</version>
+    <zip type="string">01234</zip>
+    <port type="int"> 8080 </port>
+    <empty type="null"/>
+    <name>zc</name>
+</xml>`
+	tests := []struct {
+		name    string
+		opts    Options
+		want    map[string]interface{}
+		wantErr bool
+	}{
+		{
+			name: "strings",
+			want: map[string]interface{}{
+				"count":   "5000",
+				"ratio":   "0.5",
+				"enabled": "true",
+				"missing": "null",
+				"version": "1.10",
+				"zip":     "01234",
+				"port":    int64(8080),
+				"empty":   nil,
+				"name":    "zc",
+			},
+		},
+		{
+			name: "infer-types",
+			opts: Options{InferTypes: true},
+			want: map[string]interface{}{
+				"count":   int64(5000),
+				"ratio":   0.5,
+				"enabled": true,
+				"missing": nil,
+				"version": 1.1,
+				"zip":     "01234",
+				"port":    int64(8080),
+				"empty":   nil,
+				"name":    "zc",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := &Document{Options: tt.opts}
+			err := xmle.Unmarshal([]byte(data), doc)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Unmarshal() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(doc.Value, tt.want) {
+				t.Errorf("Unmarshal() = %#v, want %#v", doc.Value, tt.want)
+			}
+		})
+	}
+
+	err := xmle.Unmarshal([]byte(`<xml><a><port type="int">x</port></a></xml>`), &Document{})
+	if err == nil || err.Error() != `element a.port: strconv.ParseInt: parsing "x": invalid syntax` {
+		t.Errorf("Unmarshal() error = %v", err)
+	}
+}
+
+func TestDocument_MarshalXML(t *testing.T) {
+	doc := Document{
+		Options: Options{TypeAttributes: true},
+		Value: map[string]interface{}{
+			"count": 5000,
+			"ratio": 0.5,
+			"on":    true,
+			"none":  nil,
+			"name":  "zc",
+		},
+	}
+	got, err := xmle.Marshal(doc)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	want := `<xml>
+    <count type="int">5000</count>
+    <name>zc</name>
+    <none type="null"></none>
+    <on type="bool">true</on>
+    <ratio type="float">0.5</ratio>
+</xml>`
+	if string(got) != want {
+		t.Errorf("Marshal() = %s, want %s", got, want)
+	}
+
+	back := &Document{}
+	if err := xmle.Unmarshal(got, back); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	wantBack := map[string]interface{}{
+		"count": int64(5000),
+		"ratio": 0.5,
+		"on":    true,
+		"none":  nil,
+		"name":  "zc",
+	}
+	if !reflect.DeepEqual(back.Value, wantBack) {
+		t.Errorf("Unmarshal() = %#v, want %#v", back.Value, wantBack)
+	}
+}
+
+func TestDocument_Attributes(t *testing.T) {
+	const data = `<project xmlns="http://maven.apache.org/POM/4.0.0" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">
+    <a id="1">x</a>
+    <input type="text" name="q"></input>
+    <weight unit="kg" type="int">5</weight>
+    <deps>
+        <dep type="array" scope="test">junit</dep>
+        <dep type="array">gson</dep>
+    </deps>
+    <svg width="10">
+        <rect height="2"></rect>
+    </svg>
+</project>`
+	opts := Options{AttrPrefix: DefaultAttrPrefix}
+	doc := &Document{Options: opts, Ordered: true}
+	if err := xmle.Unmarshal([]byte(data), doc); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	want := map[string]interface{}{
+		"@xmlns":     "http://maven.apache.org/POM/4.0.0",
+		"@xmlns:xsi": "http://www.w3.org/2001/XMLSchema-instance",
+		"a":          map[string]interface{}{"@id": "1", "#text": "x"},
+		"input":      map[string]interface{}{"@type": "text", "@name": "q"},
+		"weight":     map[string]interface{}{"@unit": "kg", "#text": int64(5)},
+		"deps": map[string]interface{}{
+			"dep": []interface{}{
+				map[string]interface{}{"@scope": "test", "#text": "junit"},
+				"gson",
+			},
+		},
+		"svg": map[string]interface{}{
+			"@width": "10",
+			"rect":   map[string]interface{}{"@height": "2"},
+		},
+	}
+	unordered := &Document{Options: opts}
+	if err := xmle.Unmarshal([]byte(data), unordered); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if !reflect.DeepEqual(unordered.Value, want) {
+		t.Errorf("Unmarshal() = %#v, want %#v", unordered.Value, want)
+	}
+
+	doc.Options.TypeAttributes = true
+	out := xmle.StartElement{Name: xmle.Name{Local: "project"}}
+	var buf bytes.Buffer
+	if err := xmle.NewEncoder(&buf).EncodeElement(doc, out); err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if buf.String() != data {
+		t.Errorf("Marshal() = %s, want %s", buf.String(), data)
+	}
+}
+
+func TestDocument_Namespaces(t *testing.T) {
+	const soap = `<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/">
+    <soap:Body xmlns:m="urn:stock">
+        <m:Price m:currency="USD">34.5</m:Price>
+        <Price>12</Price>
+    </soap:Body>
+</soap:Envelope>`
+	const env = "http://schemas.xmlsoap.org/soap/envelope/"
+	tests := []struct {
+		name string
+		opts Options
+		want map[string]interface{}
+		// encoded document, the input when empty
+		wantXML string
+	}{
+		{
+			name: "none",
+			opts: Options{},
+			want: map[string]interface{}{
+				"Body": map[string]interface{}{"Price": []interface{}{"34.5", "12"}},
+			},
+			wantXML: `<Envelope>
+    <Body>
+        <Price type="array">34.5</Price>
+        <Price type="array">12</Price>
+    </Body>
+</Envelope>`,
+		},
+		{
+			name: "prefix",
+			opts: Options{AttrPrefix: DefaultAttrPrefix, Namespaces: NamespacePrefix},
+			want: map[string]interface{}{
+				"@xmlns:soap": env,
+				"soap:Body": map[string]interface{}{
+					"@xmlns:m": "urn:stock",
+					"m:Price":  map[string]interface{}{"@m:currency": "USD", "#text": "34.5"},
+					"Price":    "12",
+				},
+			},
+		},
+		{
+			name: "prefix-without-attributes",
+			opts: Options{Namespaces: NamespacePrefix},
+			want: map[string]interface{}{
+				"xmlns:soap": env,
+				"xmlns:m":    "urn:stock",
+				"soap:Body": map[string]interface{}{
+					"m:Price": "34.5",
+					"Price":   "12",
+				},
+			},
+			wantXML: `<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/" xmlns:m="urn:stock">
+    <soap:Body>
+        <m:Price>34.5</m:Price>
+        <Price>12</Price>
+    </soap:Body>
+</soap:Envelope>`,
+		},
+		{
+			name: "uri",
+			opts: Options{
+				AttrPrefix: DefaultAttrPrefix,
+				Namespaces: NamespaceURI,
+				Prefixes:   map[string]string{"soap": env},
+			},
+			want: map[string]interface{}{
+				"{" + env + "}Body": map[string]interface{}{
+					"{urn:stock}Price": map[string]interface{}{"@{urn:stock}currency": "USD", "#text": "34.5"},
+					"Price":            "12",
+				},
+			},
+			wantXML: `<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/">
+    <soap:Body>
+        <ns1:Price xmlns:ns1="urn:stock" ns1:currency="USD">34.5</ns1:Price>
+        <Price>12</Price>
+    </soap:Body>
+</soap:Envelope>`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := &Document{Options: tt.opts}
+			if err := xmle.Unmarshal([]byte(soap), doc); err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+			if !reflect.DeepEqual(doc.Value, tt.want) {
+				t.Errorf("Unmarshal() = %#v, want %#v", doc.Value, tt.want)
+			}
+
+			doc = &Document{Options: tt.opts, Ordered: true}
+			if err := xmle.Unmarshal([]byte(soap), doc); err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+			root := "Envelope"
+			if tt.opts.Namespaces != NamespaceNone {
+				root = "soap:Envelope"
+			}
+			var buf bytes.Buffer
+			if err := xmle.NewEncoder(&buf).EncodeElement(doc, xmle.StartElement{Name: xmle.Name{Local: root}}); err != nil {
+				t.Fatalf("Marshal() error = %v", err)
+			}
+			want := tt.wantXML
+			if want == "" {
+				want = soap
+			}
+			if buf.String() != want {
+				t.Errorf("Marshal() = %s, want %s", buf.String(), want)
+			}
+		})
+	}
+}
+
+func TestDocument_DefaultNamespace(t *testing.T) {
+	const atom = `<feed xmlns="http://www.w3.org/2005/Atom">
+    <title>ditto</title>
+    <ext xmlns="">1</ext>
+</feed>`
+	opts := Options{Namespaces: NamespaceURI, Prefixes: map[string]string{"": "http://www.w3.org/2005/Atom"}}
+	doc := &Document{Options: opts, Ordered: true}
+	if err := xmle.Unmarshal([]byte(atom), doc); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	var buf bytes.Buffer
+	start := xmle.StartElement{Name: xmle.Name{Local: "{http://www.w3.org/2005/Atom}feed"}}
+	if err := xmle.NewEncoder(&buf).EncodeElement(doc, start); err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if buf.String() != atom {
+		t.Errorf("Marshal() = %s, want %s", buf.String(), atom)
+	}
+}
+
+func TestDocument_NestedArrays(t *testing.T) {
+	value := map[string]interface{}{
+		"matrix": []interface{}{
+			[]interface{}{int64(1), int64(2)},
+			[]interface{}{},
+			[]interface{}{[]interface{}{"a"}, map[string]interface{}{"b": "c"}},
+		},
+		"mixed": []interface{}{"x", map[string]interface{}{"y": "z"}, map[string]interface{}{}, "w"},
+	}
+	got, err := xmle.Marshal(Document{Options: Options{TypeAttributes: true}, Value: value})
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	want := `<xml>
+    <matrix type="list">
+        <item type="array">1</item>
+        <item type="array">2</item>
+    </matrix>
+    <matrix type="list"></matrix>
+    <matrix type="list">
+        <item type="list">
+            <item type="array">a</item>
+        </item>
+        <item type="array">
+            <b>c</b>
+        </item>
+    </matrix>
+    <mixed type="array">x</mixed>
+    <mixed type="array">
+        <y>z</y>
+    </mixed>
+    <mixed type="array"></mixed>
+    <mixed type="array">w</mixed>
+</xml>`
+	if string(got) != want {
+		t.Errorf("Marshal() = %s, want %s", got, want)
+	}
+
+	wantBack := map[string]interface{}{
+		"matrix": []interface{}{
+			[]interface{}{int64(1), int64(2)},
+			[]interface{}{},
+			[]interface{}{[]interface{}{"a"}, map[string]interface{}{"b": "c"}},
+		},
+		"mixed": []interface{}{"x", map[string]interface{}{"y": "z"}, "", "w"},
+	}
+	for _, ordered := range []bool{false, true} {
+		back := &Document{Options: Options{InferTypes: true}, Ordered: ordered}
+		if err := xmle.Unmarshal(got, back); err != nil {
+			t.Fatalf("Unmarshal() error = %v", err)
+		}
+		again, err := xmle.Marshal(Document{Options: Options{TypeAttributes: true}, Value: back.Value})
+		if err != nil {
+			t.Fatalf("Marshal() error = %v", err)
+		}
+		if !ordered && !reflect.DeepEqual(back.Value, wantBack) {
+			t.Errorf("Unmarshal() = %#v, want %#v", back.Value, wantBack)
+		}
+		if string(again) != want {
+			t.Errorf("Marshal() = %s, want %s", again, want)
+		}
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	value := map[string]interface{}{
+		"name":  "zc",
+		"empty": "",
+		"note":  "<a></a>",
+		"owner": map[string]interface{}{"id": ""},
+	}
+	tests := []struct {
+		name string
+		opts Options
+		want string
+	}{
+		{
+			name: "default",
+			want: `<xml>
+    <empty></empty>
+    <name>zc</name>
+    <note>&lt;a&gt;&lt;/a&gt;</note>
+    <owner>
+        <id></id>
+    </owner>
+</xml>`,
+		},
+		{
+			name: "compact",
+			opts: Options{Root: "config", Compact: true, Indent: "\t"},
+			want: `<config><empty></empty><name>zc</name><note>&lt;a&gt;&lt;/a&gt;</note><owner><id></id></owner></config>`,
+		},
+		{
+			name: "declaration",
+			opts: Options{Indent: "\t", Declaration: true, SelfClosing: true},
+			want: `<?xml version="1.0" encoding="UTF-8"?>
+<xml>
+	<empty/>
+	<name>zc</name>
+	<note>&lt;a&gt;&lt;/a&gt;</note>
+	<owner>
+		<id/>
+	</owner>
+</xml>`,
+		},
+		{
+			name: "compact-declaration",
+			opts: Options{Compact: true, Declaration: true, SelfClosing: true},
+			want: `<?xml version="1.0" encoding="UTF-8"?><xml><empty/><name>zc</name><note>&lt;a&gt;&lt;/a&gt;</note><owner><id/></owner></xml>`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Marshal(Document{Options: tt.opts, Value: value})
+			if err != nil {
+				t.Fatalf("Marshal() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Marshal() = %s, want %s", got, tt.want)
+			}
+
+			var buf bytes.Buffer
+			if err := NewEncoder(&buf).Encode(&Document{Options: tt.opts, Value: value}); err != nil {
+				t.Fatalf("Encode() error = %v", err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("Encode() = %s, want %s", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+func Test_selfClose(t *testing.T) {
+	const data = `<a><b x="1"></b><!-- <c></c> --><![CDATA[<d></d>]]><e></e ><f>g</f></a>`
+	const want = `<a><b x="1"/><!-- <c></c> --><![CDATA[<d></d>]]><e></e ><f>g</f></a>`
+	if got := selfClose([]byte(data)); string(got) != want {
+		t.Errorf("selfClose() = %s, want %s", got, want)
+	}
+}
+
+func TestDocument_UnmarshalErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		opts    Options
+		strict  bool
+		want    map[string]interface{}
+		wantErr error
+	}{
+		{
+			name:    "attribute-then-children",
+			data:    `<xml b="1"><a_b><c>2</c></a_b></xml>`,
+			opts:    Options{AttrPrefix: "a_"},
+			wantErr: ErrConflict,
+		},
+		{
+			name:    "attribute-then-array",
+			data:    `<xml b="1"><a_b type="array">2</a_b></xml>`,
+			opts:    Options{AttrPrefix: "a_"},
+			wantErr: ErrConflict,
+		},
+		{
+			name:    "attribute-then-list",
+			data:    `<xml b="1"><a_b type="list"><item>1</item></a_b></xml>`,
+			opts:    Options{AttrPrefix: "a_"},
+			wantErr: ErrConflict,
+		},
+		{
+			name:    "children-then-text",
+			data:    `<xml><a x="1"><t><c>1</c></t>hi</a></xml>`,
+			opts:    Options{AttrPrefix: DefaultAttrPrefix, TextKey: "t"},
+			wantErr: ErrConflict,
+		},
+		{
+			name:    "array-then-text",
+			data:    `<xml><a x="1"><t type="array">1</t>hi</a></xml>`,
+			opts:    Options{AttrPrefix: DefaultAttrPrefix, TextKey: "t"},
+			wantErr: ErrConflict,
+		},
+		{
+			name:    "value-then-children-strict",
+			data:    `<xml><a>1</a><a><b>2</b></a></xml>`,
+			strict:  true,
+			wantErr: ErrDuplicateElement,
+		},
+		{
+			name:    "array-then-value-strict",
+			data:    `<xml><a type="array">1</a><a>2</a></xml>`,
+			strict:  true,
+			wantErr: ErrDuplicateElement,
+		},
+		{
+			name:    "duplicate-strict",
+			data:    `<xml><a>1</a><a>2</a></xml>`,
+			strict:  true,
+			wantErr: ErrDuplicateElement,
+		},
+		{
+			name:   "array-strict",
+			data:   `<xml><a type="array">1</a><a type="array">2</a></xml>`,
+			strict: true,
+			want:   map[string]interface{}{"a": []interface{}{"1", "2"}},
+		},
+		{
+			name: "mixed",
+			data: `<xml><a>x<b>1</b>y</a></xml>`,
+			want: map[string]interface{}{"a": map[string]interface{}{"b": "1"}},
+		},
+		{
+			name:    "mixed-strict-leading",
+			data:    `<xml><a>x<b>1</b></a></xml>`,
+			strict:  true,
+			wantErr: ErrMixedContent,
+		},
+		{
+			name:    "mixed-strict-trailing",
+			data:    `<xml><a><b>1</b>y</a></xml>`,
+			strict:  true,
+			wantErr: ErrMixedContent,
+		},
+		{
+			name:    "mixed-strict-root",
+			data:    `<xml><a>1</a>z</xml>`,
+			strict:  true,
+			wantErr: ErrMixedContent,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := tt.opts
+			opts.Strict = tt.strict
+			doc := &Document{Options: opts}
+			err := xmle.Unmarshal([]byte(tt.data), doc)
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Fatalf("Unmarshal() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && !reflect.DeepEqual(doc.Value, tt.want) {
+				t.Errorf("Unmarshal() = %#v, want %#v", doc.Value, tt.want)
+			}
+		})
+	}
+
+	err := xmle.Unmarshal([]byte(`<xml><a b="1"><a_b><c>2</c></a_b></a></xml>`), &Document{Options: Options{AttrPrefix: "a_"}})
+	if err == nil || err.Error() != "element a.a_b.c: conflicting element: a.a_b holds a value, not child elements" {
+		t.Errorf("Unmarshal() error = %v", err)
+	}
+}
+
+func TestDocument_RepeatedElements(t *testing.T) {
+	const data = `<rss>
+    <channel>
+        <item><title>a</title><tag>x</tag><tag>y</tag></item>
+        <item><title>b</title><tag>z</tag></item>
+        <skip>1</skip>
+        <skip type="array">2</skip>
+        <skip><n>3</n></skip>
+    </channel>
+    <channel>
+        <item><title>c</title></item>
+    </channel>
+</rss>`
+	tests := []struct {
+		name       string
+		forceArray []string
+		want       map[string]interface{}
+	}{
+		{
+			name: "auto",
+			want: map[string]interface{}{
+				"channel": []interface{}{
+					map[string]interface{}{
+						"item": []interface{}{
+							map[string]interface{}{"title": "a", "tag": []interface{}{"x", "y"}},
+							map[string]interface{}{"title": "b", "tag": "z"},
+						},
+						"skip": []interface{}{"1", "2", map[string]interface{}{"n": "3"}},
+					},
+					map[string]interface{}{
+						"item": map[string]interface{}{"title": "c"},
+					},
+				},
+			},
+		},
+		{
+			name:       "force",
+			forceArray: []string{"/rss/channel/item", "/rss/channel/item/tag", "/rss/missing"},
+			want: map[string]interface{}{
+				"channel": []interface{}{
+					map[string]interface{}{
+						"item": []interface{}{
+							map[string]interface{}{"title": "a", "tag": []interface{}{"x", "y"}},
+							map[string]interface{}{"title": "b", "tag": []interface{}{"z"}},
+						},
+						"skip": []interface{}{"1", "2", map[string]interface{}{"n": "3"}},
+					},
+					map[string]interface{}{
+						"item": []interface{}{map[string]interface{}{"title": "c"}},
+					},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := &Document{Options: Options{ForceArray: tt.forceArray}}
+			if err := xmle.Unmarshal([]byte(data), doc); err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+			if !reflect.DeepEqual(doc.Value, tt.want) {
+				t.Errorf("Unmarshal() = %#v, want %#v", doc.Value, tt.want)
+			}
+		})
+	}
+}
+
+func TestDocument_Markup(t *testing.T) {
+	const data = `<!-- Licensed under the Apache License -->
+<?xml-stylesheet href="style.xsl"?>
+<page>
+    <script><![CDATA[if (a < b) { go(); }]]></script>
+    <!-- first -->
+    <title lang="en">ditto</title>
+    <!-- second -->
+    <body>text<![CDATA[<b>bold</b>]]></body>
+</page>`
+	opts := Options{Markup: true, AttrPrefix: DefaultAttrPrefix}
+	want := map[string]interface{}{
+		"#prolog": []interface{}{
+			map[string]interface{}{"#comment": " Licensed under the Apache License "},
+			map[string]interface{}{"#pi": `xml-stylesheet href="style.xsl"`},
+		},
+		"#comment": []interface{}{" first ", " second "},
+		"script":   map[string]interface{}{"#cdata": "if (a < b) { go(); }"},
+		"title":    map[string]interface{}{"@lang": "en", "#text": "ditto"},
+		"body":     map[string]interface{}{"#text": "text", "#cdata": "<b>bold</b>"},
+	}
+	doc := &Document{Options: opts}
+	if err := Unmarshal([]byte(data), doc); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if !reflect.DeepEqual(doc.Value, want) {
+		t.Errorf("Unmarshal() = %#v, want %#v", doc.Value, want)
+	}
+
+	doc = &Document{Options: opts, Ordered: true}
+	if err := NewDecoder(strings.NewReader(data)).Decode(doc); err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	doc.Options.Root = "page"
+	got, err := Marshal(doc)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	// the prolog stays before the root element, repeated comments make an array
+	wantXML := `<!-- Licensed under the Apache License -->
+<?xml-stylesheet href="style.xsl"?>
+<page>
+    <script><![CDATA[if (a < b) { go(); }]]></script>
+    <!-- first -->
+    <!-- second -->
+    <title lang="en">ditto</title>
+    <body>text<![CDATA[<b>bold</b>]]></body>
+</page>`
+	if string(got) != wantXML {
+		t.Errorf("Marshal() = %s, want %s", got, wantXML)
+	}
+
+	doc.Options.Declaration = true
+	doc.Value = ordered.Map{{Key: "#pi", Value: "a"}, {Key: "#comment", Value: "b"}, {Key: "c", Value: "d"}}
+	if got, err = Marshal(doc); err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	// encoding/xml does not indent processing instructions inside the root element
+	wantXML = Header + `
+<page><?a?>
+    <!--b-->
+    <c>d</c>
+</page>`
+	if string(got) != wantXML {
+		t.Errorf("Marshal() = %s, want %s", got, wantXML)
+	}
+
+	doc.Value = ordered.Map{{Key: "#prolog", Value: []interface{}{ordered.Map{{Key: "#comment", Value: "x"}}}}, {Key: "c", Value: "d"}}
+	doc.Options.Compact = true
+	if got, err = Marshal(doc); err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if want := Header + "<!--x--><page><c>d</c></page>"; string(got) != want {
+		t.Errorf("Marshal() = %s, want %s", got, want)
+	}
+
+	got, err = Marshal(Document{Options: Options{Markup: true}, Value: map[string]interface{}{"html": "<p>a & b</p>"}})
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if want := "<xml>\n    <html><![CDATA[<p>a & b</p>]]></html>\n</xml>"; string(got) != want {
+		t.Errorf("Marshal() = %s, want %s", got, want)
+	}
+}
+
+func TestDocument_MixedContent(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    Options
+		root    string
+		data    string
+		want    map[string]interface{}
+		wantXML string
+	}{
+		{
+			name: "inline elements",
+			opts: Options{MixedContent: true},
+			root: "p",
+			data: `<p>Hello <b>world</b>!</p>`,
+			want: map[string]interface{}{
+				"#content": []interface{}{"Hello ", map[string]interface{}{"b": "world"}, "!"},
+			},
+			wantXML: `<p>Hello <b>world</b>!</p>`,
+		},
+		{
+			name: "nested",
+			opts: Options{MixedContent: true, AttrPrefix: DefaultAttrPrefix},
+			root: "doc",
+			data: `<doc><p class="intro">See <a href="/x">the <em>docs</em></a>, <b>a</b> and <b>b</b>.<br/></p><title>Guide</title></doc>`,
+			want: map[string]interface{}{
+				"p": map[string]interface{}{
+					"@class": "intro",
+					"#content": []interface{}{
+						"See ",
+						map[string]interface{}{"a": map[string]interface{}{
+							"@href":    "/x",
+							"#content": []interface{}{"the ", map[string]interface{}{"em": "docs"}},
+						}},
+						", ",
+						map[string]interface{}{"b": "a"},
+						" and ",
+						map[string]interface{}{"b": "b"},
+						".",
+						map[string]interface{}{"br": ""},
+					},
+				},
+				"title": "Guide",
+			},
+			wantXML: `<doc>
+    <p class="intro">See <a href="/x">the <em>docs</em></a>, <b>a</b> and <b>b</b>.<br></br></p>
+    <title>Guide</title>
+</doc>`,
+		},
+		{
+			name: "array members and markup",
+			opts: Options{MixedContent: true, Markup: true},
+			root: "p",
+			data: `<p>Run <code type="array">go</code> <!-- note -->with <![CDATA[<flags>]]></p>`,
+			want: map[string]interface{}{
+				"#content": []interface{}{
+					"Run ",
+					map[string]interface{}{"code": []interface{}{"go"}},
+					" ",
+					map[string]interface{}{"#comment": " note "},
+					"with ",
+					map[string]interface{}{"#cdata": "<flags>"},
+				},
+			},
+			wantXML: `<p>Run <code type="array">go</code> <!-- note -->with <![CDATA[<flags>]]></p>`,
+		},
+		{
+			name: "strict",
+			opts: Options{MixedContent: true, Strict: true},
+			root: "p",
+			data: `<p>a<b>b</b></p>`,
+			want: map[string]interface{}{
+				"#content": []interface{}{"a", map[string]interface{}{"b": "b"}},
+			},
+			wantXML: `<p>a<b>b</b></p>`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := &Document{Options: tt.opts}
+			if err := Unmarshal([]byte(tt.data), doc); err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+			if !reflect.DeepEqual(doc.Value, tt.want) {
+				t.Errorf("Unmarshal() = %#v, want %#v", doc.Value, tt.want)
+			}
+
+			doc = &Document{Options: tt.opts, Ordered: true}
+			if err := Unmarshal([]byte(tt.data), doc); err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+			doc.Options.Root = tt.root
+			got, err := Marshal(doc)
+			if err != nil {
+				t.Fatalf("Marshal() error = %v", err)
+			}
+			if string(got) != tt.wantXML {
+				t.Errorf("Marshal() = %s, want %s", got, tt.wantXML)
+			}
+		})
+	}
+}